@@ -1,12 +1,15 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/rs/zerolog/log" // See https://github.com/spf13/viper/issues/1152
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"go.uber.org/automaxprocs/maxprocs"
@@ -18,8 +21,71 @@ const (
 	// ArgLogLevel is the command line argument and viper key to set the
 	// loglevel of the application.
 	ArgLogLevel = "loglevel"
+
+	// ArgLogLevels is the viper key below which the log levels of single
+	// components are set, e.g. "loglevels.httpserver.tls".
+	ArgLogLevels = "loglevels"
+
+	// ArgLogFormat is the command line argument and viper key to set the
+	// log output format of the application. See logging.Format for the
+	// supported values.
+	ArgLogFormat = "logformat"
+
+	// ArgLogFilePath is the command line argument and viper key to write
+	// logs to a rotating file instead of stderr.
+	ArgLogFilePath = "logfile.path"
+
+	// ArgLogFileMaxSize is the command line argument and viper key to set
+	// the size at which the log file is rotated, e.g. "100MB".
+	ArgLogFileMaxSize = "logfile.maxsize"
+
+	// ArgLogFileMaxAge is the command line argument and viper key to set
+	// the age at which the log file is rotated, e.g. "24h".
+	ArgLogFileMaxAge = "logfile.maxage"
+
+	// ArgLogFileMaxBackups is the command line argument and viper key to set
+	// the number of rotated log files to keep.
+	ArgLogFileMaxBackups = "logfile.maxbackups"
+
+	// ArgLogFileRetention is the command line argument and viper key to set
+	// the age after which rotated log files are deleted, e.g. "168h".
+	ArgLogFileRetention = "logfile.retention"
+
+	// ArgLogFileCompress is the command line argument and viper key to
+	// compress rotated log files.
+	ArgLogFileCompress = "logfile.compress"
 )
 
+// configLog is used for all log events of this package.
+var configLog = logging.Component("config")
+
+var (
+	// readGuard serializes Read and protects lastRead.
+	readGuard sync.Mutex
+
+	// lastRead holds the arguments of the last call to Read, or nil when
+	// Read was not called yet.
+	lastRead *readArgs
+
+	// levelRead reports whether a valid log level was read before.
+	levelRead bool
+
+	// componentsRead holds the components with a level read before.
+	componentsRead = map[string]struct{}{}
+)
+
+// fallbackLogLevel is used when the configured log level is invalid and no
+// valid level was read before.
+const fallbackLogLevel = "info"
+
+// readArgs are the arguments passed to Read.
+type readArgs struct {
+	// envPrefix is the prefix of environment variables.
+	envPrefix string
+	// configFile is the path of the config file, may be empty.
+	configFile string
+}
+
 var (
 	// SkipArgs defines the number of command line arguments to skip
 	// during parameter parsing in the InitConfig function.
@@ -31,6 +97,10 @@ var (
 	// DefaultLogLevel defines the log level the system should run at by default
 	DefaultLogLevel = "debug"
 
+	// DefaultLogFormat defines the log output format the system should use
+	// by default
+	DefaultLogFormat = string(logging.FormatJSON)
+
 	// ExtraArgs contains the commandline flags left after parsing in the
 	// InitConfig function.
 	ExtraArgs = []string{}
@@ -42,17 +112,52 @@ var (
 // fileformat supported by viper (e.g. ".yaml").
 // Use viper.SetDefault to set default values for configuration parameters.
 func Read(envPrefix, configFile string) {
+	readGuard.Lock()
+	defer readGuard.Unlock()
+
+	lastRead = &readArgs{envPrefix: envPrefix, configFile: configFile}
+	read(envPrefix, configFile)
+}
+
+// Reload reads the configuration again, using the arguments of the last call
+// to Read. This updates the log levels, and the log format and file when they
+// changed. Log files are reopened by the logging package on SIGHUP.
+// Does nothing when Read was not called yet.
+// Note that viper is not safe for concurrent use, so values should not be
+// read from viper while reloading.
+func Reload() {
+	readGuard.Lock()
+	defer readGuard.Unlock()
+
+	if lastRead == nil {
+		configLog.Warn().Msg("Configuration was not read yet, skipping reload.")
+		return
+	}
+
+	configLog.Info().Msg("Reloading configuration.")
+	read(lastRead.envPrefix, lastRead.configFile)
+}
+
+// read implements Read.
+func read(envPrefix, configFile string) {
 	// Default values
 	viper.SetDefault(ArgLogLevel, DefaultLogLevel)
+	viper.SetDefault(ArgLogFormat, DefaultLogFormat)
+	viper.SetDefault(ArgLogFilePath, "")
+	viper.SetDefault(ArgLogFileMaxSize, "")
+	viper.SetDefault(ArgLogFileMaxAge, time.Duration(0))
+	viper.SetDefault(ArgLogFileMaxBackups, 0)
+	viper.SetDefault(ArgLogFileRetention, time.Duration(0))
+	viper.SetDefault(ArgLogFileCompress, false)
 
 	// Allow reading from config file
 	if len(configFile) > 0 {
 		directory := filepath.Dir(configFile)
-		fileType := filepath.Ext(configFile)
-		fileName := strings.TrimSuffix(filepath.Base(configFile), fileType)
+		fileExt := filepath.Ext(configFile)
+		fileName := strings.TrimSuffix(filepath.Base(configFile), fileExt)
 
 		viper.SetConfigName(fileName)
-		viper.SetConfigType(fileType)
+		viper.SetConfigType(strings.TrimPrefix(fileExt, "."))
 
 		if len(directory) > 0 {
 			viper.AddConfigPath(directory)
@@ -62,7 +167,7 @@ func Read(envPrefix, configFile string) {
 
 		if err := viper.ReadInConfig(); err != nil {
 			// Make sure this is logged _after_ the logging has been set up
-			defer log.Info().Err(err).Msgf("Failed to read config file %s.", configFile)
+			defer configLog.Info().Err(err).Msgf("Failed to read config file %s.", configFile)
 		}
 	}
 
@@ -74,31 +179,131 @@ func Read(envPrefix, configFile string) {
 	// Allow reading from command line flags
 	err := viperAutomaticFlags()
 
-	// Setup global loglevel
-	logging.SetLogLevel(viper.GetString(ArgLogLevel))
+	// Setup global logger and loglevel
+	formatErr := configureLogOutput()
+	levelErr := logging.SetLogLevel(viper.GetString(ArgLogLevel))
+	if levelErr == nil {
+		levelRead = true
+	} else if !levelRead {
+		// Don't fall back to DefaultLogLevel, which might be very verbose.
+		_ = logging.SetLogLevel(fallbackLogLevel)
+	}
+	componentErr := setComponentLevels(envPrefix)
 
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to process command line arguments.")
+		configLog.Error().Err(err).Msg("Failed to process command line arguments.")
+	}
+
+	if formatErr != nil {
+		configLog.Error().Err(formatErr).Msg("Failed to configure log output, keeping the previous output.")
+	}
+
+	if levelErr != nil {
+		configLog.Error().Err(levelErr).Msgf("Failed to set log level, keeping %s.", logging.GetLogLevel())
+	}
+
+	if componentErr != nil {
+		configLog.Error().Err(componentErr).Msg("Failed to set component log levels.")
 	}
 
 	// Make application cgroups aware
 	// Needs to happen after the logger has been set up.
 	_, err = maxprocs.Set(maxprocs.Logger(func(format string, a ...interface{}) {
-		log.Info().Msgf(format, a...)
+		configLog.Info().Msgf(format, a...)
 	}))
 
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to configure maxprocs to match container CPU quota.")
+		configLog.Error().Err(err).Msg("Failed to configure maxprocs to match container CPU quota.")
+	}
+}
+
+// configureLogOutput applies the log format and file read from viper to the
+// global logger. All other options, e.g. set through logging.Configure
+// before Read, are kept. The logger is only replaced when the format or file
+// options changed.
+func configureLogOutput() error {
+	current := logging.GetOptions()
+
+	options := current
+	options.Format = logging.Format(strings.ToLower(viper.GetString(ArgLogFormat)))
+	options.File = logging.FileOptions{
+		Path:       viper.GetString(ArgLogFilePath),
+		MaxSize:    int64(viper.GetSizeInBytes(ArgLogFileMaxSize)),
+		MaxAge:     viper.GetDuration(ArgLogFileMaxAge),
+		MaxBackups: viper.GetInt(ArgLogFileMaxBackups),
+		Retention:  viper.GetDuration(ArgLogFileRetention),
+		Compress:   viper.GetBool(ArgLogFileCompress),
+	}
+
+	if options.Format == current.Format && options.File == current.File {
+		return nil
 	}
+	return logging.Configure(options)
+}
+
+// setComponentLevels applies the log levels of all keys below ArgLogLevels,
+// e.g. "loglevels.httpserver.tls". Levels are read from the config file or
+// from environment variables like CFG_LOGLEVELS_HTTPSERVER_TLS, with the
+// environment taking precedence. Levels read before but no longer configured
+// are reset.
+func setComponentLevels(envPrefix string) error {
+	levels := map[string]string{}
+
+	keyPrefix := ArgLogLevels + "."
+	for _, key := range viper.AllKeys() {
+		// Flags created for keys of an earlier read keep their value as
+		// default, which must not count as configured.
+		if component, found := strings.CutPrefix(key, keyPrefix); found && viper.IsSet(key) {
+			levels[component] = viper.GetString(key)
+		}
+	}
+
+	// Viper does not list keys that are only set via environment.
+	envKeyPrefix := strings.ToUpper(ArgLogLevels) + "_"
+	if len(envPrefix) > 0 {
+		envKeyPrefix = strings.ToUpper(envPrefix) + "_" + envKeyPrefix
+	}
+	for _, env := range os.Environ() {
+		name, value, _ := strings.Cut(env, "=")
+		if component, found := strings.CutPrefix(name, envKeyPrefix); found && len(component) > 0 {
+			levels[strings.ToLower(strings.ReplaceAll(component, "_", "."))] = value
+		}
+	}
+
+	for component := range componentsRead {
+		if _, found := levels[component]; !found {
+			logging.ResetComponentLevel(component)
+			delete(componentsRead, component)
+		}
+	}
+
+	errs := []error{}
+	for component, level := range levels {
+		if err := logging.SetComponentLevel(component, level); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", component, err))
+			continue
+		}
+		componentsRead[component] = struct{}{}
+	}
+	return errors.Join(errs...)
 }
 
 // viperAutomaticFlags converts all keys with a default value into command line
 // flags. Each flag supports a shorthand form, using the first character. If
-// two flags have the same first character, the first flag will have a short
-// form, the second one will not.
+// two flags have the same first character, the first flag in alphabetical
+// order will have a short form, the second one will not. The shorthand -l is
+// reserved for ArgLogLevel, other log keys of this package have no
+// shorthand.
 func viperAutomaticFlags() error {
-	usedShorts := map[string]struct{}{}
+	usedShorts := map[string]struct{}{"l": {}}
 	getShort := func(k string) string {
+		switch {
+		case k == ArgLogLevel:
+			return "l"
+		case isLogKey(k):
+			return ""
+		}
+
 		short := k[0:1]
 		if _, taken := usedShorts[short]; !taken {
 			usedShorts[short] = struct{}{}
@@ -110,7 +315,11 @@ func viperAutomaticFlags() error {
 	flagSet := pflag.NewFlagSet(FlagsName, pflag.ExitOnError)
 	flagSet.SetOutput(os.Stdout)
 
-	for _, key := range viper.AllKeys() {
+	// AllKeys returns the keys in random order.
+	keys := viper.AllKeys()
+	slices.Sort(keys)
+
+	for _, key := range keys {
 		switch v := viper.Get(key).(type) {
 		case bool:
 			flagSet.BoolP(key, getShort(key), v, "")
@@ -128,7 +337,7 @@ func viperAutomaticFlags() error {
 			flagSet.DurationP(key, getShort(key), v, "")
 		}
 
-		log.Debug().Msgf("%s = %v", key, viper.Get(key))
+		configLog.Debug().Msgf("%s = %v", key, viper.Get(key))
 	}
 
 	defer func() {
@@ -145,3 +354,10 @@ func viperAutomaticFlags() error {
 
 	return nil
 }
+
+// isLogKey reports whether key configures the logger, i.e. it is ArgLogLevel,
+// ArgLogFormat or a key below logfile or ArgLogLevels.
+func isLogKey(key string) bool {
+	return key == ArgLogLevel || key == ArgLogFormat ||
+		strings.HasPrefix(key, "logfile.") || strings.HasPrefix(key, ArgLogLevels+".")
+}
@@ -0,0 +1,151 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trivago/go-bootstrap/v2/logging"
+)
+
+// resetConfig clears the global state of viper and this package. Tests
+// using it must not run in parallel.
+func resetConfig(t *testing.T) {
+	t.Helper()
+
+	viper.Reset()
+	lastRead = nil
+	levelRead = false
+	for component := range componentsRead {
+		logging.ResetComponentLevel(component)
+	}
+	clear(componentsRead)
+
+	// Skip the arguments of the test binary.
+	skipArgs := SkipArgs
+	SkipArgs = len(os.Args) - 1
+	t.Cleanup(func() {
+		SkipArgs = skipArgs
+		viper.Reset()
+		_ = logging.SetLogLevel("error")
+	})
+}
+
+// writeConfigFile writes content to the YAML config file at path.
+func writeConfigFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+// TestReadInvalidLogLevel verifies an invalid log level keeps the previous
+// valid level and never falls back to a verbose level.
+func TestReadInvalidLogLevel(t *testing.T) {
+	resetConfig(t)
+
+	t.Setenv("TEST_LOGLEVEL", "infp")
+	Read("TEST", "")
+	assert.Equal(t, zerolog.InfoLevel, logging.GetLogLevel())
+
+	t.Setenv("TEST_LOGLEVEL", "warn")
+	Reload()
+	assert.Equal(t, zerolog.WarnLevel, logging.GetLogLevel())
+
+	t.Setenv("TEST_LOGLEVEL", "infp")
+	Reload()
+	assert.Equal(t, zerolog.WarnLevel, logging.GetLogLevel())
+}
+
+// TestReadLogLevelShorthand verifies -l always sets the log level, even
+// though other log keys start with the same character.
+func TestReadLogLevelShorthand(t *testing.T) {
+	resetConfig(t)
+
+	args := os.Args
+	os.Args = append(args[:len(args):len(args)], "-l", "warn")
+	t.Cleanup(func() { os.Args = args })
+
+	// Reload parses the flags again, the shorthand must not change.
+	Read("TEST", "")
+	for range 10 {
+		assert.Equal(t, zerolog.WarnLevel, logging.GetLogLevel())
+		Reload()
+	}
+}
+
+// TestReadComponentLevels verifies component levels are read from the
+// config file next to the root level, and reset once they are removed.
+func TestReadComponentLevels(t *testing.T) {
+	tests := []struct {
+		// name describes the test case.
+		name string
+		// content is the content of the config file.
+		content string
+	}{
+		{
+			name:    "nested",
+			content: "loglevel: warn\nloglevels:\n  httpserver:\n    tls: debug\n",
+		},
+		{
+			name:    "dotted",
+			content: "loglevel: warn\nloglevels.httpserver.tls: debug\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetConfig(t)
+
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			writeConfigFile(t, path, tt.content)
+			Read("TEST", path)
+
+			assert.Equal(t, zerolog.WarnLevel, logging.GetLogLevel())
+			assert.Equal(t, zerolog.DebugLevel, logging.ComponentLevels()["httpserver.tls"])
+
+			writeConfigFile(t, path, "loglevel: warn\n")
+			Reload()
+
+			assert.Equal(t, zerolog.WarnLevel, logging.GetLogLevel())
+			assert.NotContains(t, logging.ComponentLevels(), "httpserver.tls")
+		})
+	}
+}
+
+// TestReloadKeepsLogOptions verifies reading the configuration only changes
+// the log format and file, keeping options set through logging.Configure.
+func TestReloadKeepsLogOptions(t *testing.T) {
+	resetConfig(t)
+	t.Cleanup(func() {
+		require.NoError(t, logging.Configure(logging.Options{}))
+	})
+
+	output := &bytes.Buffer{}
+	require.NoError(t, logging.Configure(logging.Options{
+		Output:      output,
+		Caller:      true,
+		DedupWindow: time.Hour,
+	}))
+
+	Read("TEST", "")
+	assert.Equal(t, logging.FormatJSON, logging.GetOptions().Format)
+
+	t.Setenv("TEST_LOGFORMAT", "logfmt")
+	Reload()
+
+	options := logging.GetOptions()
+	assert.Equal(t, logging.FormatLogfmt, options.Format)
+	assert.Same(t, output, options.Output)
+	assert.True(t, options.Caller)
+	assert.Equal(t, time.Hour, options.DedupWindow)
+
+	t.Setenv("TEST_LOGFORMAT", "xml")
+	Reload()
+	assert.Equal(t, logging.FormatLogfmt, logging.GetOptions().Format)
+}
@@ -9,8 +9,8 @@ import (
 	"time"
 
 	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/log"
 	jww "github.com/spf13/jwalterweatherman" // See https://github.com/spf13/viper/issues/1152
+	"github.com/trivago/go-bootstrap/v2/logging"
 )
 
 const (
@@ -22,29 +22,38 @@ const (
 	realIPHeader = "X-Real-IP"
 )
 
-// logThresholdsOnce ensures jwalterweatherman is aligned once per process.
-var logThresholdsOnce sync.Once
+// logThresholdsGuard serializes updates of the jwalterweatherman thresholds,
+// as jwalterweatherman is not safe for concurrent use.
+var logThresholdsGuard sync.Mutex
 
-// syncLogThresholds aligns jwalterweatherman verbosity with zerolog.
+// syncLogThresholds aligns jwalterweatherman verbosity with zerolog. It is
+// called whenever the global zerolog level changes.
 func syncLogThresholds() {
-	logThresholdsOnce.Do(func() {
-		switch zerolog.GlobalLevel() {
-		default:
-			fallthrough
-		case zerolog.DebugLevel:
-			jww.SetLogThreshold(jww.LevelDebug)
-			jww.SetStdoutThreshold(jww.LevelDebug)
-		case zerolog.InfoLevel:
-			jww.SetLogThreshold(jww.LevelInfo)
-			jww.SetStdoutThreshold(jww.LevelInfo)
-		case zerolog.WarnLevel:
-			jww.SetLogThreshold(jww.LevelWarn)
-			jww.SetStdoutThreshold(jww.LevelWarn)
-		case zerolog.ErrorLevel:
-			jww.SetLogThreshold(jww.LevelError)
-			jww.SetStdoutThreshold(jww.LevelError)
-		}
-	})
+	logThresholdsGuard.Lock()
+	defer logThresholdsGuard.Unlock()
+
+	var threshold jww.Threshold
+	switch logging.GetLogLevel() {
+	case zerolog.TraceLevel:
+		threshold = jww.LevelTrace
+	default:
+		fallthrough
+	case zerolog.DebugLevel:
+		threshold = jww.LevelDebug
+	case zerolog.InfoLevel:
+		threshold = jww.LevelInfo
+	case zerolog.WarnLevel:
+		threshold = jww.LevelWarn
+	case zerolog.ErrorLevel:
+		threshold = jww.LevelError
+	case zerolog.FatalLevel:
+		threshold = jww.LevelCritical
+	case zerolog.PanicLevel, zerolog.Disabled:
+		threshold = jww.LevelFatal
+	}
+
+	jww.SetLogThreshold(threshold)
+	jww.SetStdoutThreshold(threshold)
 }
 
 // shouldSkipAccessLog reports whether path is excluded from access logging.
@@ -94,11 +103,11 @@ func writeAccessLog(
 	var event *zerolog.Event
 	switch {
 	case len(errMsg) > 0:
-		event = log.Warn().Err(fmt.Errorf("%s", errMsg))
+		event = accessLog.Warn().Err(fmt.Errorf("%s", errMsg))
 	case status >= 500:
-		event = log.Warn()
+		event = accessLog.Warn()
 	default:
-		event = log.Info()
+		event = accessLog.Info()
 	}
 
 	event.Str("latency", latency.String()).
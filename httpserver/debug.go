@@ -0,0 +1,176 @@
+package httpserver
+
+import (
+	"crypto/subtle"
+	"errors"
+	"expvar"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"net/netip"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+)
+
+// debugPathPrefix is the path prefix of all debug endpoints. It is fixed, as
+// the pprof index links to absolute paths below /debug/pprof/.
+const debugPathPrefix = "/debug/"
+
+// DebugConfig enables pprof and expvar endpoints below /debug/, i.e.
+// /debug/pprof/ and /debug/vars. The endpoints are served on the management
+// port, if configured, and are never written to the access log.
+type DebugConfig struct {
+	// Enabled mounts the debug endpoints.
+	Enabled bool
+
+	// BasicAuthUser and BasicAuthPassword protect the debug endpoints with
+	// HTTP basic authentication. Both or none must be set.
+	BasicAuthUser string
+
+	// BasicAuthPassword is the password for BasicAuthUser.
+	BasicAuthPassword string
+
+	// AllowedNetworks restricts the debug endpoints to clients from the
+	// given IP addresses or CIDR networks, e.g. "10.0.0.0/8". The peer
+	// address is used, proxy headers are ignored.
+	// When empty, all clients are allowed.
+	AllowedNetworks []string
+}
+
+// newDebugHandler creates the handler for all debug endpoints. Returns nil
+// when the endpoints are disabled. mainPort reports whether the endpoints
+// are served on the main port, i.e. without a management port, in which
+// case a warning is logged when they are not protected.
+func newDebugHandler(config DebugConfig, mainPort bool) (http.Handler, error) {
+	if !config.Enabled {
+		return nil, nil
+	}
+
+	hasUser, hasPassword := len(config.BasicAuthUser) > 0, len(config.BasicAuthPassword) > 0
+	if hasUser != hasPassword {
+		return nil, errors.New("debug basic authentication requires both user and password")
+	}
+
+	allowed, err := parseNetworks(config.AllowedNetworks)
+	if err != nil {
+		return nil, err
+	}
+
+	if mainPort && !hasUser && len(allowed) == 0 {
+		serverLog.Warn().Msg("Debug endpoints are served on the main port without basic authentication or allowed networks.")
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(debugPathPrefix+"pprof/", pprof.Index)
+	mux.HandleFunc(debugPathPrefix+"pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc(debugPathPrefix+"pprof/profile", pprof.Profile)
+	mux.HandleFunc(debugPathPrefix+"pprof/symbol", pprof.Symbol)
+	mux.HandleFunc(debugPathPrefix+"pprof/trace", pprof.Trace)
+	mux.Handle(debugPathPrefix+"vars", expvar.Handler())
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if !isAllowedPeer(allowed, request.RemoteAddr) {
+			http.Error(writer, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		if hasUser && !hasBasicAuth(request, config.BasicAuthUser, config.BasicAuthPassword) {
+			writer.Header().Set("WWW-Authenticate", `Basic realm="debug"`)
+			http.Error(writer, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(writer, request)
+	}), nil
+}
+
+// parseNetworks converts IP addresses and CIDR networks into prefixes.
+func parseNetworks(networks []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(networks))
+	for _, network := range networks {
+		if strings.Contains(network, "/") {
+			prefix, err := netip.ParsePrefix(network)
+			if err != nil {
+				return nil, fmt.Errorf("invalid debug network %q: %w", network, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(network)
+		if err != nil {
+			return nil, fmt.Errorf("invalid debug network %q: %w", network, err)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+	}
+	return prefixes, nil
+}
+
+// isAllowedPeer reports whether the peer address is part of one of the
+// allowed networks. All peers are allowed when the list is empty.
+func isAllowedPeer(allowed []netip.Prefix, peerAddr string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+
+	host, _, err := net.SplitHostPort(peerAddr)
+	if err != nil {
+		host = peerAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+
+	addr = addr.Unmap()
+	for _, prefix := range allowed {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasBasicAuth reports whether the request carries the expected basic
+// authentication credentials.
+func hasBasicAuth(request *http.Request, expectedUser, expectedPassword string) bool {
+	user, password, ok := request.BasicAuth()
+	if !ok {
+		return false
+	}
+	userMatch := subtle.ConstantTimeCompare([]byte(user), []byte(expectedUser))
+	passwordMatch := subtle.ConstantTimeCompare([]byte(password), []byte(expectedPassword))
+	return userMatch&passwordMatch == 1
+}
+
+// withDebugHTTP dispatches requests below debugPathPrefix to debug, bypassing
+// next and its access log. Returns next when debug is nil.
+func withDebugHTTP(debug http.Handler, next http.Handler) http.Handler {
+	if debug == nil {
+		return next
+	}
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if strings.HasPrefix(request.URL.Path, debugPathPrefix) {
+			debug.ServeHTTP(writer, request)
+			return
+		}
+		next.ServeHTTP(writer, request)
+	})
+}
+
+// withDebugFastHTTP dispatches requests below debugPathPrefix to debug,
+// bypassing next and its access log. Returns next when debug is nil.
+func withDebugFastHTTP(debug http.Handler, next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	if debug == nil {
+		return next
+	}
+	adapted := fasthttpadaptor.NewFastHTTPHandler(debug)
+	return func(ctx *fasthttp.RequestCtx) {
+		if strings.HasPrefix(string(ctx.Path()), debugPathPrefix) {
+			adapted(ctx)
+			return
+		}
+		next(ctx)
+	}
+}
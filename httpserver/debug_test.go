@@ -0,0 +1,230 @@
+package httpserver
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trivago/go-bootstrap/v2/logging"
+	"github.com/valyala/fasthttp"
+)
+
+// TestDebugHandlerAccess verifies the IP allow-list and basic
+// authentication of the debug endpoints.
+func TestDebugHandlerAccess(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// name identifies the test case.
+		name string
+		// config is the debug configuration under test.
+		config DebugConfig
+		// remoteAddr is the peer address of the request.
+		remoteAddr string
+		// user and password are sent as basic authentication when user is
+		// not empty.
+		user string
+		// password is the basic authentication password.
+		password string
+		// wantStatus is the expected response status.
+		wantStatus int
+	}{
+		{
+			name:       "open",
+			config:     DebugConfig{Enabled: true},
+			remoteAddr: "192.0.2.1:1234",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "allowed network",
+			config:     DebugConfig{Enabled: true, AllowedNetworks: []string{"10.0.0.0/8", "192.0.2.1"}},
+			remoteAddr: "10.1.2.3:1234",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "allowed address",
+			config:     DebugConfig{Enabled: true, AllowedNetworks: []string{"10.0.0.0/8", "192.0.2.1"}},
+			remoteAddr: "192.0.2.1:1234",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "denied address",
+			config:     DebugConfig{Enabled: true, AllowedNetworks: []string{"10.0.0.0/8"}},
+			remoteAddr: "192.0.2.1:1234",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "missing credentials",
+			config:     DebugConfig{Enabled: true, BasicAuthUser: "admin", BasicAuthPassword: "secret"},
+			remoteAddr: "192.0.2.1:1234",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "wrong credentials",
+			config:     DebugConfig{Enabled: true, BasicAuthUser: "admin", BasicAuthPassword: "secret"},
+			remoteAddr: "192.0.2.1:1234",
+			user:       "admin",
+			password:   "wrong",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "valid credentials",
+			config:     DebugConfig{Enabled: true, BasicAuthUser: "admin", BasicAuthPassword: "secret"},
+			remoteAddr: "192.0.2.1:1234",
+			user:       "admin",
+			password:   "secret",
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler, err := newDebugHandler(tt.config, false)
+			require.NoError(t, err)
+
+			request := httptest.NewRequest(http.MethodGet, "/debug/vars", nil)
+			request.RemoteAddr = tt.remoteAddr
+			if len(tt.user) > 0 {
+				request.SetBasicAuth(tt.user, tt.password)
+			}
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			assert.Equal(t, tt.wantStatus, recorder.Code)
+		})
+	}
+}
+
+// TestDebugHandlerConfig verifies disabled endpoints and invalid networks.
+func TestDebugHandlerConfig(t *testing.T) {
+	t.Parallel()
+
+	handler, err := newDebugHandler(DebugConfig{}, true)
+	assert.NoError(t, err)
+	assert.Nil(t, handler)
+
+	_, err = newDebugHandler(DebugConfig{Enabled: true, BasicAuthUser: "admin"}, false)
+	assert.Error(t, err)
+
+	_, err = newDebugHandler(DebugConfig{Enabled: true, BasicAuthPassword: "secret"}, false)
+	assert.Error(t, err)
+
+	_, err = NewWithConfig(Config{Debug: DebugConfig{
+		Enabled:         true,
+		AllowedNetworks: []string{"not-an-ip"},
+	}}, nil)
+	assert.Error(t, err)
+}
+
+// TestDebugHandlerWarning verifies a warning is logged when the debug
+// endpoints are served on the main port without protection. The test
+// modifies the global logger and must not run in parallel.
+func TestDebugHandlerWarning(t *testing.T) {
+	output := &bytes.Buffer{}
+	require.NoError(t, logging.Configure(logging.Options{Output: output}))
+	previous := logging.GetLogLevel()
+	require.NoError(t, logging.SetLogLevel("warn"))
+	t.Cleanup(func() {
+		require.NoError(t, logging.SetLogLevel(previous.String()))
+		require.NoError(t, logging.Configure(logging.Options{}))
+	})
+
+	tests := []struct {
+		// name describes the test case.
+		name string
+		// config is the debug configuration under test.
+		config DebugConfig
+		// mainPort serves the endpoints on the main port.
+		mainPort bool
+		// wantWarning expects a warning to be logged.
+		wantWarning bool
+	}{
+		{name: "main port unprotected", config: DebugConfig{Enabled: true}, mainPort: true, wantWarning: true},
+		{name: "management port", config: DebugConfig{Enabled: true}},
+		{
+			name:     "basic auth",
+			config:   DebugConfig{Enabled: true, BasicAuthUser: "admin", BasicAuthPassword: "secret"},
+			mainPort: true,
+		},
+		{
+			name:     "allowed networks",
+			config:   DebugConfig{Enabled: true, AllowedNetworks: []string{"10.0.0.0/8"}},
+			mainPort: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output.Reset()
+
+			_, err := newDebugHandler(tt.config, tt.mainPort)
+			require.NoError(t, err)
+
+			if tt.wantWarning {
+				assert.Contains(t, output.String(), `"severity":"WARNING"`)
+			} else {
+				assert.Empty(t, output.String())
+			}
+		})
+	}
+}
+
+// TestDebugEndpoints verifies the debug endpoints are mounted on all server
+// types, on the management server when configured.
+func TestDebugEndpoints(t *testing.T) {
+	t.Parallel()
+
+	debug := DebugConfig{Enabled: true}
+	serve := func(handler http.Handler, path string) int {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder.Code
+	}
+	serveFast := func(handler fasthttp.RequestHandler, path string) int {
+		ctx := &fasthttp.RequestCtx{}
+		ctx.Request.SetRequestURI(path)
+		handler(ctx)
+		return ctx.Response.StatusCode()
+	}
+	notFound := http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.WriteHeader(http.StatusNotFound)
+	})
+	fastNotFound := func(ctx *fasthttp.RequestCtx) {
+		ctx.SetStatusCode(fasthttp.StatusNotFound)
+	}
+
+	for _, path := range []string{"/debug/vars", "/debug/pprof/"} {
+		srv, err := NewWithConfig(Config{Debug: debug}, notFound)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, serve(srv.Server.Handler, path), "net/http %s", path)
+
+		fastSrv, err := NewFastHTTPWithConfig(Config{Debug: debug}, fastNotFound)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, serveFast(fastSrv.Server.Handler, path), "fasthttp %s", path)
+
+		ginSrv, err := NewGinWithConfig(GinConfig{Debug: debug})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, serve(ginSrv.Server.Handler, path), "gin %s", path)
+
+		srv, err = NewWithConfig(Config{Debug: debug, ManagementPort: 9090}, notFound)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, serve(srv.Server.Handler, path), "net/http main %s", path)
+		assert.Equal(t, http.StatusOK, serve(srv.Management.Handler, path), "net/http management %s", path)
+
+		fastSrv, err = NewFastHTTPWithConfig(Config{Debug: debug, ManagementPort: 9090}, fastNotFound)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, serveFast(fastSrv.Server.Handler, path), "fasthttp main %s", path)
+		assert.Equal(t, http.StatusOK, serve(fastSrv.Management.Handler, path), "fasthttp management %s", path)
+
+		ginSrv, err = NewGinWithConfig(GinConfig{Debug: debug, ManagementPort: 9090})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, serve(ginSrv.Server.Handler, path), "gin main %s", path)
+		assert.Equal(t, http.StatusOK, serve(ginSrv.Management.Handler, path), "gin management %s", path)
+	}
+}
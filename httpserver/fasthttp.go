@@ -2,10 +2,11 @@ package httpserver
 
 import (
 	"context"
+	"net"
+	"net/http"
 	"slices"
 	"time"
 
-	"github.com/rs/zerolog/log"
 	"github.com/valyala/fasthttp"
 )
 
@@ -16,15 +17,39 @@ type FastHTTPServer struct {
 	// additional fields before Listen.
 	Server *fasthttp.Server
 
+	// Management is the server for probes and admin endpoints when a
+	// management port is configured, nil otherwise. It is started and
+	// stopped together with Server.
+	Management *http.Server
+
+	// state tracks the startup and readiness reported by the probes.
+	state *lifecycle
+
+	// cert is the TLS certificate handler, nil when TLS is disabled.
+	cert *fileBasedCert
+
 	// addr is the listen address used by ListenAndServe.
 	addr string
 
+	// network is the network of addr, "tcp4" when empty.
+	network string
+
+	// listener is the pre-bound listener to serve on, if any.
+	listener net.Listener
+
+	// bound records the listen address and when serving started.
+	bound binding
+
+	// managementBound records the listen address of Management and when it
+	// started serving.
+	managementBound binding
+
 	// useTLS reports whether TLS should be enabled.
 	useTLS bool
 }
 
 // NewFastHTTP creates a fasthttp server with the given port, probe checks,
-// and handler. Access logs for /healthz and /readyz are disabled by default.
+// and handler. Access logs for the probes are disabled by default.
 func NewFastHTTP(
 	port int,
 	health, ready Check,
@@ -46,75 +71,137 @@ func NewFastHTTPWithConfig(
 ) (*FastHTTPServer, error) {
 	syncLogThresholds()
 
-	tlsConfig, err := buildTLSConfig(config)
+	tlsConfig, cert, err := buildTLSConfig(config)
 	if err != nil {
 		return nil, err
 	}
 
-	wrapped := wrapFastHTTPHandler(config, handler)
+	debug, err := newDebugHandler(config.Debug, config.ManagementPort <= 0)
+	if err != nil {
+		return nil, err
+	}
+	warnLogLevelOnMainPort(config.LogLevelPath, config.ManagementPort <= 0)
+
+	state := newLifecycle(config)
+	wrapped := wrapFastHTTPHandler(config, state, handler)
+	if config.ManagementPort <= 0 {
+		wrapped = withDebugFastHTTP(debug, wrapped)
+	}
+
+	server := &fasthttp.Server{
+		Handler:   wrapped,
+		TLSConfig: tlsConfig,
+		Logger:    &fastHTTPLogger{},
+	}
+	applyFastHTTPLimits(server, config.Limits)
 
 	return &FastHTTPServer{
-		Server: &fasthttp.Server{
-			Handler:   wrapped,
-			TLSConfig: tlsConfig,
-			Logger:    &fastHTTPLogger{},
-		},
-		addr:   resolveAddr(config),
-		useTLS: tlsConfig != nil,
+		Server:     server,
+		Management: newManagementServer(config, newManagementHandler(config, state, debug)),
+		state:      state,
+		cert:       cert,
+		addr:       resolveAddr(config),
+		network:    resolveNetwork(config, "tcp4"),
+		listener:   config.Listener,
+		useTLS:     tlsConfig != nil,
 	}, nil
 }
 
-// ListenAndServe starts the server and blocks until it stops. Expected
-// shutdown results are normalized to a nil error.
+// ListenAndServe starts the server and the management server, if any, and
+// blocks until both stop. Expected shutdown results are normalized to a nil
+// error.
 func (s *FastHTTPServer) ListenAndServe() error {
-	var err error
+	return serveWithManagement(s.Management, &s.managementBound, s.listenAndServe, s.Server.ShutdownWithContext)
+}
+
+// listenAndServe starts the main server and blocks until it stops. A
+// pre-bound or inherited listener is used when available.
+func (s *FastHTTPServer) listenAndServe() error {
+	network := s.network
+	if len(network) == 0 {
+		network = "tcp4"
+	}
+
+	listener, err := serveListener(s.listener, network, s.addr)
+	if err != nil {
+		return err
+	}
+	listener = s.bound.bind(listener)
+
 	if s.useTLS {
-		err = s.Server.ListenAndServeTLS(s.addr, "", "")
+		if err = s.Server.ServeTLS(listener, "", ""); err != nil {
+			// ServeTLS only closes the listener once serving started.
+			_ = listener.Close()
+		}
 	} else {
-		err = s.Server.ListenAndServe(s.addr)
+		err = s.Server.Serve(listener)
 	}
 
 	if err == nil {
-		log.Warn().Msg("HTTP server was instructed to close")
+		serverLog.Warn().Msg("HTTP server was instructed to close")
 	}
 	return err
 }
 
-// Shutdown gracefully stops the underlying fasthttp server.
+// Shutdown gracefully stops the underlying fasthttp server, then the
+// management server, if any.
 func (s *FastHTTPServer) Shutdown(ctx context.Context) error {
-	return s.Server.ShutdownWithContext(ctx)
+	return shutdownWithManagement(ctx, s.Management, s.Server.ShutdownWithContext)
 }
 
 // wrapFastHTTPHandler installs recovery, access logging, and probe routes
-// around the application handler.
-func wrapFastHTTPHandler(config Config, handler fasthttp.RequestHandler) fasthttp.RequestHandler {
+// around the application handler. Probes are left out when a management port
+// is configured.
+func wrapFastHTTPHandler(config Config, state *lifecycle, handler fasthttp.RequestHandler) fasthttp.RequestHandler {
 	if handler == nil {
 		handler = func(ctx *fasthttp.RequestCtx) {}
 	}
 
+	logLevel := logLevelFastHTTP(config.LogLevelTTL)
+	health := probeFastHTTP("healthz", withDefaultCheck(config.HealthChecks, config.Health), config.CheckTimeout)
+	ready := probeFastHTTP("readyz", state.readyChecks(withDefaultCheck(config.ReadyChecks, config.Ready)), config.CheckTimeout)
+	startup := probeFastHTTP("startupz", state.startupChecks(withDefaultCheck(config.StartupChecks, config.Startup)), config.CheckTimeout)
+
 	withProbes := func(ctx *fasthttp.RequestCtx) {
+		if config.ManagementPort > 0 {
+			// Probes and admin endpoints are served by the management server.
+			handler(ctx)
+			return
+		}
+		if len(config.LogLevelPath) > 0 && string(ctx.Path()) == config.LogLevelPath {
+			logLevel(ctx)
+			return
+		}
 		if ctx.IsGet() {
 			switch string(ctx.Path()) {
 			case healthPath:
-				ctx.SetStatusCode(probeStatus(ctx, config.Health))
+				health(ctx)
 				return
 			case readyPath:
-				ctx.SetStatusCode(probeStatus(ctx, config.Ready))
+				ready(ctx)
+				return
+			case startupPath:
+				startup(ctx)
 				return
 			}
 		}
 		handler(ctx)
 	}
 
-	withRecovery := recoverFastHTTP(withProbes)
+	withRecovery := recoverFastHTTP(config.PanicHandler, withProbes)
 	return accessLogFastHTTP(config.DisableAccessLogFor, withRecovery)
 }
 
 // accessLogFastHTTP logs each request after the next handler returns.
+// Requests aborted with http.ErrAbortHandler are not logged, like for
+// net/http.
 func accessLogFastHTTP(ignorePaths []string, next fasthttp.RequestHandler) fasthttp.RequestHandler {
 	return func(ctx *fasthttp.RequestCtx) {
 		started := time.Now()
 		next(ctx)
+		if aborted, _ := ctx.UserValue(abortedKey{}).(bool); aborted {
+			return
+		}
 		writeAccessLog(
 			ignorePaths,
 			string(ctx.Path()),
@@ -131,26 +218,10 @@ func accessLogFastHTTP(ignorePaths []string, next fasthttp.RequestHandler) fasth
 	}
 }
 
-// recoverFastHTTP converts panics into HTTP 500 responses.
-func recoverFastHTTP(next fasthttp.RequestHandler) fasthttp.RequestHandler {
-	return func(ctx *fasthttp.RequestCtx) {
-		defer func() {
-			if recovered := recover(); recovered != nil {
-				log.Error().Interface("panic", recovered).Msg("Recovered from panic")
-				ctx.Error(
-					fasthttp.StatusMessage(fasthttp.StatusInternalServerError),
-					fasthttp.StatusInternalServerError,
-				)
-			}
-		}()
-		next(ctx)
-	}
-}
-
 // fastHTTPLogger adapts fasthttp server logs to zerolog.
 type fastHTTPLogger struct{}
 
 // Printf writes a fasthttp log line as an error event.
 func (fastHTTPLogger) Printf(format string, args ...any) {
-	log.Error().Msgf(format, args...)
+	serverLog.Error().Msgf(format, args...)
 }
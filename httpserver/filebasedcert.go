@@ -7,8 +7,6 @@ import (
 	"os"
 	"sync"
 	"time"
-
-	"github.com/rs/zerolog/log"
 )
 
 // fileBasedCert is a certificate handler that is reloading the certificate from
@@ -65,7 +63,7 @@ func (c *fileBasedCert) GetCertificate() (*tls.Certificate, error) {
 			// server startup if the certificate is expired. We will just keep
 			// using the expired certificate, which will be result in an error
 			// for the client.
-			log.Warn().Msg("reloaded TLS certificate has already expired.")
+			tlsLog.Warn().Msg("reloaded TLS certificate has already expired.")
 
 			// When certCacheDuration is set to a value higher than one minute,
 			// we will retry within the next minute. This is to make sure that
@@ -83,24 +81,24 @@ func (c *fileBasedCert) GetCertificate() (*tls.Certificate, error) {
 	switch {
 	// Load the certificate from disk if we don't have one cached yet.
 	case c.cert == nil:
-		log.Info().Msg("No TLS certificate cached, loading.")
+		tlsLog.Info().Msg("No TLS certificate cached, loading.")
 		return reload()
 
 	// Reload the certificate if it has expired.
 	case now.After(c.cert.Leaf.NotAfter):
-		log.Warn().Msg("TLS certificate has expired, reloading.")
+		tlsLog.Warn().Msg("TLS certificate has expired, reloading.")
 		return reload()
 
 	// Check for a new certificate in regular intervals.
 	// We only change the loaded certificate if the signature has changed.
 	case now.Sub(c.lastRefresh) > c.certCacheDuration:
-		log.Info().Msg("TLS certificate cache duration has passed.")
+		tlsLog.Info().Msg("TLS certificate cache duration has passed.")
 
 		// Check if the certificate file has been changed since the last
 		// refresh. This is a simple check that only compares the modification
 		// time of the file.
 		if fileInfo, err := os.Stat(c.certFile); err == nil && fileInfo.ModTime().After(c.lastRefresh) {
-			log.Warn().Msg("TLS certificate file has been modified since last refresh.")
+			tlsLog.Warn().Msg("TLS certificate file has been modified since last refresh.")
 			return reload()
 		}
 
@@ -109,15 +107,42 @@ func (c *fileBasedCert) GetCertificate() (*tls.Certificate, error) {
 		// of the certificate.
 		cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
 		if err != nil || cert.Leaf == nil {
-			log.Error().Err(err).Msg("Failed to load TLS certificate for comparison, keeping cached certificate.")
+			tlsLog.Error().Err(err).Msg("Failed to load TLS certificate for comparison, keeping cached certificate.")
 			return c.cert, nil
 		}
 
 		if !bytes.Equal(cert.Leaf.Signature, c.cert.Leaf.Signature) {
-			log.Warn().Msg("Detected certificate signature change, reloading.")
+			tlsLog.Warn().Msg("Detected certificate signature change, reloading.")
 			return reload()
 		}
 	}
 
 	return c.cert, nil
 }
+
+// Reload loads the certificate from disk, regardless of certCacheDuration.
+// The cached certificate is kept when loading fails. Does nothing on a nil
+// handler, i.e. when TLS is disabled.
+func (c *fileBasedCert) Reload() error {
+	if c == nil {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	switch {
+	case err != nil:
+		return err
+	case cert.Leaf == nil:
+		return fmt.Errorf("certificate leaf is nil")
+	case time.Now().After(cert.Leaf.NotAfter):
+		tlsLog.Warn().Msg("reloaded TLS certificate has already expired.")
+	}
+
+	c.certGuard.Lock()
+	defer c.certGuard.Unlock()
+
+	c.cert = &cert
+	c.lastRefresh = time.Now()
+	tlsLog.Info().Msg("TLS certificate reloaded.")
+	return nil
+}
@@ -1,7 +1,7 @@
 package httpserver
 
 import (
-	golog "log"
+	"net"
 	"net/http"
 	"slices"
 	"sync"
@@ -21,6 +21,38 @@ type GinConfig struct {
 	// Defaults to 8080, or 8443 for TLS when left empty.
 	Port int
 
+	// ListenAddr defines the address the server will listen on, e.g.
+	// "127.0.0.1:8080" to bind a single interface or ":0" for a random
+	// port. For the "unix" network, it is the path of the socket file.
+	// Overrides Port when set.
+	ListenAddr string
+
+	// Network defines the network of ListenAddr: "tcp", "tcp4", "tcp6" or
+	// "unix". A stale socket file of a previous process is removed.
+	// Defaults to "tcp".
+	Network string
+
+	// Listener defines a pre-bound listener to serve on, e.g. a ":0"
+	// listener in tests. Overrides Port, ListenAddr and Network. The
+	// listener is closed when the server shuts down.
+	Listener net.Listener
+
+	// Limits defines timeouts and size limits protecting the server against
+	// slow or misbehaving clients, see LimitsConfig for the defaults.
+	Limits LimitsConfig
+
+	// ManagementPort enables a second, plain HTTP listener on the given port
+	// serving the probes, the LogLevelPath endpoint and InitManagementRoutes.
+	// These endpoints are no longer served on Port. The management server
+	// is started and stopped together with the main server.
+	// When left empty, all endpoints are served on Port.
+	ManagementPort int
+
+	// InitManagementRoutes defines a function that will be called to
+	// register additional routes, e.g. metrics, on the management server.
+	// Only used when ManagementPort is set.
+	InitManagementRoutes func(router *gin.Engine)
+
 	// Health defines the handler for the /healthz endpoint.
 	// When nil, AlwaysOk is used.
 	Health gin.HandlerFunc
@@ -29,10 +61,46 @@ type GinConfig struct {
 	// When nil, AlwaysOk is used.
 	Ready gin.HandlerFunc
 
+	// HealthChecks defines named checks for the /healthz endpoint, see
+	// Config.HealthChecks. Only used when Health is nil.
+	HealthChecks Checks
+
+	// ReadyChecks defines named checks for the /readyz endpoint, see
+	// Config.ReadyChecks. Only used when Ready is nil.
+	ReadyChecks Checks
+
+	// Startup defines the handler for the /startupz endpoint.
+	// When nil, AlwaysOk is used.
+	Startup gin.HandlerFunc
+
+	// StartupChecks defines named checks for the /startupz endpoint, see
+	// Config.StartupChecks. Only used when Startup is nil.
+	StartupChecks Checks
+
+	// CheckTimeout bounds the duration of each check.
+	// Defaults to 1 second when left empty.
+	CheckTimeout time.Duration
+
+	// DeferStartup lets /startupz and /readyz fail until MarkStarted or
+	// MarkReady is called, see Config.DeferStartup.
+	DeferStartup bool
+
+	// DeferReady lets /readyz fail until MarkReady is called, see
+	// Config.DeferReady.
+	DeferReady bool
+
+	// DrainDelay defines how long Listen keeps serving with /readyz failing
+	// before the graceful shutdown begins, see Config.DrainDelay.
+	DrainDelay time.Duration
+
 	// DisableAccessLogFor defines a list of paths for which the access log
 	// will not be written. The path must match exactly, including case.
 	DisableAccessLogFor []string
 
+	// Middleware defines Gin middlewares registered after access logging
+	// and panic recovery, but before the probes and all routes.
+	Middleware []gin.HandlerFunc
+
 	// InitRoutes defines a function that will be called to configure routes
 	// on this server. Use it to define the handler for your routes.
 	InitRoutes func(router *gin.Engine)
@@ -48,6 +116,36 @@ type GinConfig struct {
 	// CertCacheDuration defines how long a certificate will be cached in
 	// memory before it is reloaded from disk. Default duration is 7 days.
 	CertCacheDuration time.Duration
+
+	// LogLevelPath defines the path of an admin endpoint to read and change
+	// the global log level. GET returns the current level, PUT and POST set
+	// it from the "level" query parameter. An optional "ttl" query parameter
+	// reverts the change after the given duration, e.g. "ttl=10m".
+	// The endpoint is not authenticated. It is served on the public port
+	// unless ManagementPort is set, so anyone reaching the server can change
+	// the log level. A warning is logged when it is served on the public port.
+	// When left empty, the endpoint is disabled.
+	LogLevelPath string
+
+	// LogLevelTTL defines after which duration a log level change through
+	// LogLevelPath is reverted when no "ttl" query parameter is given.
+	// When zero, such changes are kept until the next change.
+	LogLevelTTL time.Duration
+
+	// Debug enables pprof and expvar endpoints below /debug/. They are
+	// served on the management port, if configured.
+	Debug DebugConfig
+
+	// PanicHandler is called after a panic in a handler has been recovered.
+	// Use it to notify external systems or to customize the response.
+	// When nil, a 500 Internal Server Error is returned.
+	PanicHandler PanicHandler
+
+	// ErrorLogRules defines the rules detecting the level of the lines the
+	// net/http server writes to its ErrorLog, e.g. TLS handshake errors.
+	// Lines without a matching rule are logged as errors.
+	// When nil, logging.DefaultLevelRules are used.
+	ErrorLogRules []logging.LevelRule
 }
 
 // AlwaysOk is a Gin handler that always returns HTTP 200 OK.
@@ -57,8 +155,8 @@ func AlwaysOk(ctx *gin.Context) {
 }
 
 // NewGin creates a Gin-backed HTTP server with the given port, probe
-// handlers, and route initializer. Access logs for /healthz and /readyz are
-// disabled by default.
+// handlers, and route initializer. Access logs for the probes are disabled by
+// default.
 func NewGin(
 	port int,
 	health, ready gin.HandlerFunc,
@@ -80,38 +178,127 @@ func NewGinWithConfig(config GinConfig) (*HTTPServer, error) {
 	syncLogThresholds()
 	configureGinMode()
 
-	tlsConfig, err := buildTLSConfig(config.asConfig())
+	tlsConfig, cert, err := buildTLSConfig(config.asConfig())
 	if err != nil {
 		return nil, err
 	}
 
+	debug, err := newDebugHandler(config.Debug, config.ManagementPort <= 0)
+	if err != nil {
+		return nil, err
+	}
+	warnLogLevelOnMainPort(config.LogLevelPath, config.ManagementPort <= 0)
+
+	state := newLifecycle(config.asConfig())
+
 	router := gin.New()
-	router.Use(newGinZeroLogLogger(config.DisableAccessLogFor), gin.Recovery())
+	router.Use(
+		accessLogGin(config.DisableAccessLogFor),
+		recoverGin(config.PanicHandler),
+		limitRequestsGin(limitOrDefault(config.Limits.Concurrency, 0), ginAdminPaths(config)),
+		limitBodyGin(config.Limits.maxRequestBodySize()),
+	)
+	router.Use(config.Middleware...)
+
+	var management *http.Server
+	if config.ManagementPort > 0 {
+		managementRouter := gin.New()
+		managementRouter.Use(
+			accessLogGin(config.DisableAccessLogFor),
+			recoverGin(config.PanicHandler),
+			limitBodyGin(config.Limits.maxRequestBodySize()),
+		)
+		registerGinAdminRoutes(managementRouter, config, state)
+		if config.InitManagementRoutes != nil {
+			config.InitManagementRoutes(managementRouter)
+		}
+		management = newManagementServer(config.asConfig(), withDebugHTTP(debug, managementRouter))
+	} else {
+		registerGinAdminRoutes(router, config, state)
+	}
+
+	var handler http.Handler = router
+	if config.ManagementPort <= 0 {
+		handler = withDebugHTTP(debug, router)
+	}
+
+	if config.InitRoutes != nil {
+		config.InitRoutes(router)
+	}
+
+	server := &http.Server{
+		Addr:      resolveAddr(config.asConfig()),
+		Handler:   handler,
+		ErrorLog:  newErrorLog(config.ErrorLogRules),
+		TLSConfig: tlsConfig,
+	}
+	applyHTTPLimits(server, config.Limits)
 
+	return &HTTPServer{
+		Server:     server,
+		Management: management,
+		state:      state,
+		cert:       cert,
+		network:    resolveNetwork(config.asConfig(), "tcp"),
+		listener:   config.Listener,
+	}, nil
+}
+
+// registerGinAdminRoutes registers the probes and the log level endpoint.
+// Custom probe handlers are only called once state allows it.
+func registerGinAdminRoutes(router *gin.Engine, config GinConfig, state *lifecycle) {
 	health := config.Health
+	if health == nil && len(config.HealthChecks) > 0 {
+		health = probeGin("healthz", config.HealthChecks, config.CheckTimeout)
+	}
 	if health == nil {
 		health = AlwaysOk
 	}
+
 	ready := config.Ready
+	if readyChecks := state.readyChecks(config.ReadyChecks); ready == nil && len(readyChecks) > 0 {
+		ready = probeGin("readyz", readyChecks, config.CheckTimeout)
+	} else if ready != nil && state.gatesReady() {
+		ready = gateGin("readyz", readyCheckName, state.checkReady, ready)
+	}
 	if ready == nil {
 		ready = AlwaysOk
 	}
 
+	startup := config.Startup
+	if startupChecks := state.startupChecks(config.StartupChecks); startup == nil && len(startupChecks) > 0 {
+		startup = probeGin("startupz", startupChecks, config.CheckTimeout)
+	} else if startup != nil && state.deferStartup {
+		startup = gateGin("startupz", startedCheckName, state.checkStarted, startup)
+	}
+	if startup == nil {
+		startup = AlwaysOk
+	}
+
 	router.GET(healthPath, health)
 	router.GET(readyPath, ready)
+	router.GET(startupPath, startup)
 
-	if config.InitRoutes != nil {
-		config.InitRoutes(router)
+	if len(config.LogLevelPath) > 0 {
+		logLevel := logLevelGin(config.LogLevelTTL)
+		router.GET(config.LogLevelPath, logLevel)
+		router.PUT(config.LogLevelPath, logLevel)
+		router.POST(config.LogLevelPath, logLevel)
 	}
+}
 
-	return &HTTPServer{
-		Server: &http.Server{
-			Addr:      resolveAddr(config.asConfig()),
-			Handler:   router,
-			ErrorLog:  golog.New(logging.ErrorLogWriter{}, "", 0),
-			TLSConfig: tlsConfig,
-		},
-	}, nil
+// ginAdminPaths returns the paths registered by registerGinAdminRoutes on
+// the main router, i.e. none when they are served by the management server.
+func ginAdminPaths(config GinConfig) []string {
+	if config.ManagementPort > 0 {
+		return nil
+	}
+
+	paths := []string{healthPath, readyPath, startupPath}
+	if len(config.LogLevelPath) > 0 {
+		paths = append(paths, config.LogLevelPath)
+	}
+	return paths
 }
 
 // asConfig maps Gin-specific settings onto the shared Config used for port
@@ -119,45 +306,66 @@ func NewGinWithConfig(config GinConfig) (*HTTPServer, error) {
 func (config GinConfig) asConfig() Config {
 	return Config{
 		Port:                config.Port,
+		ListenAddr:          config.ListenAddr,
+		Network:             config.Network,
+		Listener:            config.Listener,
+		Limits:              config.Limits,
+		ManagementPort:      config.ManagementPort,
+		HealthChecks:        config.HealthChecks,
+		ReadyChecks:         config.ReadyChecks,
+		StartupChecks:       config.StartupChecks,
+		CheckTimeout:        config.CheckTimeout,
+		DeferStartup:        config.DeferStartup,
+		DeferReady:          config.DeferReady,
+		DrainDelay:          config.DrainDelay,
 		DisableAccessLogFor: config.DisableAccessLogFor,
 		PathTLSCert:         config.PathTLSCert,
 		PathTLSKey:          config.PathTLSKey,
 		CertCacheDuration:   config.CertCacheDuration,
+		LogLevelPath:        config.LogLevelPath,
+		LogLevelTTL:         config.LogLevelTTL,
+		PanicHandler:        config.PanicHandler,
+		ErrorLogRules:       config.ErrorLogRules,
+		Debug:               config.Debug,
 	}
 }
 
-// configureGinMode disables console color and selects Gin release mode when
-// the global zerolog level is above debug.
+// configureGinMode disables console color, routes Gin's console output
+// through zerolog and selects Gin release mode when the global log level is
+// above debug.
 func configureGinMode() {
 	ginModeOnce.Do(func() {
 		gin.DisableConsoleColor()
 		gin.DefaultWriter = logging.DebugLogWriter{}
 
-		if zerolog.GlobalLevel() > zerolog.DebugLevel {
+		errorWriter := logging.NewClassifyingWriter(zerolog.ErrorLevel)
+		errorWriter.Log = serverLog.WithLevel
+		gin.DefaultErrorWriter = errorWriter
+
+		if logging.GetLogLevel() > zerolog.DebugLevel {
 			gin.SetMode(gin.ReleaseMode)
 		}
 	})
 }
 
-// newGinZeroLogLogger returns Gin middleware that writes structured access
-// logs through zerolog.
-func newGinZeroLogLogger(ignorePaths []string) gin.HandlerFunc {
-	return gin.LoggerWithConfig(gin.LoggerConfig{
-		Output: logging.NullWriter{}, // output is done through the formatter
-		Formatter: func(params gin.LogFormatterParams) string {
-			// Gin already resolves ClientIP from X-Forwarded-For, then
-			// X-Real-IP, then the peer address — the same contract as
-			// resolveClientIP used by the net/http and fasthttp servers.
-			writeAccessLog(
-				ignorePaths,
-				params.Path,
-				params.StatusCode,
-				params.Method,
-				params.ClientIP,
-				params.Latency,
-				params.ErrorMessage,
-			)
-			return ""
-		},
-	})
+// accessLogGin is a Gin middleware logging each request after the following
+// handlers return. Private Gin errors are added to the entry.
+func accessLogGin(ignorePaths []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		started := time.Now()
+		// Handlers may rewrite the request, so the path is read upfront.
+		path := c.Request.URL.Path
+
+		c.Next()
+
+		writeAccessLog(
+			ignorePaths,
+			path,
+			c.Writer.Status(),
+			c.Request.Method,
+			c.ClientIP(),
+			time.Since(started),
+			c.Errors.ByType(gin.ErrorTypePrivate).String(),
+		)
+	}
 }
@@ -11,7 +11,7 @@ import (
 )
 
 // TestNewGinBehaviors covers InitRoutes delegation, default probes, custom
-// Gin probe status codes, and a nil InitRoutes callback.
+// Gin probe status codes, middlewares, and a nil InitRoutes callback.
 func TestNewGinBehaviors(t *testing.T) {
 	t.Parallel()
 
@@ -72,6 +72,30 @@ func TestNewGinBehaviors(t *testing.T) {
 			path:       "/healthz",
 			wantStatus: http.StatusOK,
 		},
+		{
+			name: "middleware runs before probes",
+			config: GinConfig{
+				Middleware: []gin.HandlerFunc{
+					func(ctx *gin.Context) {
+						ctx.AbortWithStatus(http.StatusUnauthorized)
+					},
+				},
+			},
+			path:       "/healthz",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "panicking middleware is recovered",
+			config: GinConfig{
+				Middleware: []gin.HandlerFunc{
+					func(*gin.Context) {
+						panic("boom")
+					},
+				},
+			},
+			path:       "/readyz",
+			wantStatus: http.StatusInternalServerError,
+		},
 		{
 			name: "explicit always ok ready",
 			config: GinConfig{
@@ -122,7 +146,7 @@ func TestNewGinConvenience(t *testing.T) {
 		})
 	})
 	require.NoError(t, err)
-	assert.Equal(t, []string{"/healthz", "/readyz"}, defaultDisableAccessLogFor)
+	assert.Equal(t, []string{"/healthz", "/readyz", "/startupz"}, defaultDisableAccessLogFor)
 
 	listener := startHTTPServer(t, srv)
 	baseURL := "http://" + listener.Addr().String()
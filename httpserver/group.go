@@ -0,0 +1,172 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// groupStopInterval is how often a group repeats the shutdown of servers
+// which didn't exit yet after another server failed.
+const groupStopInterval = 100 * time.Millisecond
+
+// Group runs several servers under one lifecycle, e.g. a public Gin API and
+// an internal fasthttp endpoint. It implements Server, Drainer and Reloader,
+// so it can be passed to Run and Listen.
+type Group []Server
+
+// ListenAndServe starts all servers concurrently and blocks until all of
+// them stopped. When one server fails, all other servers are shut down,
+// bounded by the timeout passed to WithShutdownTimeout when the group is
+// started by Run or Listen, or 30 seconds otherwise. The errors of all
+// servers are joined.
+func (group Group) ListenAndServe() error {
+	return group.listenAndServe(defaultShutdownTimeout)
+}
+
+// listenAndServe implements ListenAndServe, shutting down the servers
+// bounded by shutdownTimeout when one of them fails.
+func (group Group) listenAndServe(shutdownTimeout time.Duration) error {
+	var (
+		failOnce sync.Once
+		failed   = make(chan struct{})
+		exited   = make([]chan struct{}, len(group))
+		errs     = make([]error, len(group))
+	)
+
+	for i, srv := range group {
+		exited[i] = make(chan struct{})
+		go func() {
+			defer close(exited[i])
+			if err := listenAndServe(srv, shutdownTimeout); err != nil {
+				errs[i] = fmt.Errorf("server %d: %w", i, err)
+				failOnce.Do(func() {
+					close(failed)
+				})
+			}
+		}()
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		select {
+		case <-failed:
+			serverLog.Error().Msg("Server of group failed, stopping all servers")
+			group.stopAll(exited, shutdownTimeout)
+		case <-stopped:
+		}
+	}()
+
+	for _, done := range exited {
+		<-done
+	}
+	close(stopped)
+
+	// All writes to errs happened before the exited channels were closed.
+	return errors.Join(errs...)
+}
+
+// stopAll shuts down all servers which did not exit yet. The shutdown is
+// repeated until they exit or timeout expires, as a server may ignore a
+// shutdown requested before it started serving.
+func (group Group) stopAll(exited []chan struct{}, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var wait sync.WaitGroup
+	for i, srv := range group {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+
+			ticker := time.NewTicker(groupStopInterval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-exited[i]:
+					return
+				default:
+				}
+
+				if err := srv.Shutdown(ctx); err != nil {
+					serverLog.Error().Err(err).Int("server", i).Msg("Graceful shutdown failed")
+				}
+
+				select {
+				case <-exited[i]:
+					return
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}()
+	}
+	wait.Wait()
+}
+
+// listenAndServe starts srv, passing shutdownTimeout on to groups.
+func listenAndServe(srv Server, shutdownTimeout time.Duration) error {
+	if group, ok := srv.(Group); ok {
+		return group.listenAndServe(shutdownTimeout)
+	}
+	return srv.ListenAndServe()
+}
+
+// Shutdown gracefully stops all servers concurrently. The errors of all
+// servers are joined.
+func (group Group) Shutdown(ctx context.Context) error {
+	var wait sync.WaitGroup
+	errs := make([]error, len(group))
+
+	for i, srv := range group {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			if err := srv.Shutdown(ctx); err != nil {
+				errs[i] = fmt.Errorf("server %d: %w", i, err)
+			}
+		}()
+	}
+
+	wait.Wait()
+	return errors.Join(errs...)
+}
+
+// MarkDraining marks all servers implementing Drainer as draining.
+func (group Group) MarkDraining() {
+	for _, srv := range group {
+		if drainer, ok := srv.(Drainer); ok {
+			drainer.MarkDraining()
+		}
+	}
+}
+
+// DrainDelay returns the longest drain delay of all servers implementing
+// Drainer.
+func (group Group) DrainDelay() time.Duration {
+	var delay time.Duration
+	for _, srv := range group {
+		if drainer, ok := srv.(Drainer); ok {
+			delay = max(delay, drainer.DrainDelay())
+		}
+	}
+	return delay
+}
+
+// Reload reloads all servers implementing Reloader. The errors of all
+// servers are joined.
+func (group Group) Reload(ctx context.Context) error {
+	errs := []error{}
+	for i, srv := range group {
+		if reloader, ok := srv.(Reloader); ok {
+			if err := reloader.Reload(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("server %d: %w", i, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,147 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGroupFailure verifies all servers of a group are stopped when one of
+// them fails to start.
+func TestGroupFailure(t *testing.T) {
+	t.Parallel()
+
+	srv, err := NewWithConfig(Config{}, nil)
+	require.NoError(t, err)
+	srv.Server.Addr = "127.0.0.1:0"
+
+	fastSrv, err := NewFastHTTPWithConfig(Config{}, nil)
+	require.NoError(t, err)
+	fastSrv.addr = "127.0.0.1:0"
+
+	failing := &fakeServer{
+		stopped: make(chan struct{}),
+		serve: func(*fakeServer) error {
+			return errors.New("address already in use")
+		},
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- Group{srv, fastSrv, failing}.ListenAndServe()
+	}()
+
+	select {
+	case err := <-done:
+		assert.EqualError(t, err, "server 2: address already in use")
+	case <-time.After(5 * time.Second):
+		t.Fatal("ListenAndServe did not return")
+	}
+}
+
+// deadlineServer is a fakeServer recording the deadline of its first
+// shutdown.
+type deadlineServer struct {
+	*fakeServer
+	// deadlines receives the deadline of the first shutdown.
+	deadlines chan time.Time
+}
+
+// Shutdown records the deadline of ctx and unblocks ListenAndServe.
+func (srv deadlineServer) Shutdown(ctx context.Context) error {
+	deadline, _ := ctx.Deadline()
+	select {
+	case srv.deadlines <- deadline:
+	default:
+	}
+	return srv.fakeServer.Shutdown(ctx)
+}
+
+// TestGroupShutdownTimeout verifies the servers of a group are shut down
+// bounded by the timeout passed to Run when one of them fails.
+func TestGroupShutdownTimeout(t *testing.T) {
+	t.Parallel()
+
+	failing := &fakeServer{
+		stopped: make(chan struct{}),
+		serve: func(*fakeServer) error {
+			return errors.New("address already in use")
+		},
+	}
+	recording := deadlineServer{
+		fakeServer: &fakeServer{
+			stopped: make(chan struct{}),
+			serve: func(srv *fakeServer) error {
+				<-srv.stopped
+				return nil
+			},
+		},
+		deadlines: make(chan time.Time, 1),
+	}
+
+	started := time.Now()
+	err := Run(context.Background(), Group{failing, recording}, WithShutdownTimeout(time.Hour))
+	assert.ErrorIs(t, err, ErrStartup)
+
+	select {
+	case deadline := <-recording.deadlines:
+		assert.WithinDuration(t, started.Add(time.Hour), deadline, time.Minute)
+	default:
+		t.Fatal("server was not shut down")
+	}
+}
+
+// TestGroupShutdown verifies a group stops all servers on Shutdown.
+func TestGroupShutdown(t *testing.T) {
+	t.Parallel()
+
+	blocking := func(srv *fakeServer) error {
+		<-srv.stopped
+		return nil
+	}
+	first := &fakeServer{stopped: make(chan struct{}), serve: blocking}
+	second := &fakeServer{
+		stopped:     make(chan struct{}),
+		serve:       blocking,
+		shutdownErr: context.DeadlineExceeded,
+	}
+	group := Group{first, second}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- group.ListenAndServe()
+	}()
+
+	err := group.Shutdown(context.Background())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "server 1")
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("ListenAndServe did not return")
+	}
+}
+
+// TestGroupDrainer verifies a group drains all servers supporting it.
+func TestGroupDrainer(t *testing.T) {
+	t.Parallel()
+
+	short, err := NewWithConfig(Config{DrainDelay: time.Second}, nil)
+	require.NoError(t, err)
+	long, err := NewFastHTTPWithConfig(Config{DrainDelay: 2 * time.Second}, nil)
+	require.NoError(t, err)
+
+	group := Group{short, long, &fakeServer{}}
+	assert.Equal(t, 2*time.Second, group.DrainDelay())
+
+	group.MarkDraining()
+	assert.True(t, short.state.draining.Load())
+	assert.True(t, long.state.draining.Load())
+	assert.Zero(t, Group{}.DrainDelay())
+}
@@ -0,0 +1,18 @@
+//go:build !unix
+
+package httpserver
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// inheritReadyPipe is a no-op, as file descriptors can't be passed to new
+// processes on this platform.
+func inheritReadyPipe(string) {}
+
+// startHandover is not supported on this platform.
+func startHandover(string, []string, time.Duration) (*os.Process, error) {
+	return nil, errors.New("listener handover is not supported on this platform")
+}
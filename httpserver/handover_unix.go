@@ -0,0 +1,114 @@
+//go:build unix
+
+package httpserver
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// inheritReadyPipe picks up the pipe announced through readyFDEnv by the
+// process which started this one.
+func inheritReadyPipe(readyFD string) {
+	fd, err := strconv.Atoi(readyFD)
+	if err != nil || fd < listenFDsStart {
+		return
+	}
+
+	// Processes started later on must not keep the pipe open.
+	syscall.CloseOnExec(fd)
+
+	listenerGuard.Lock()
+	defer listenerGuard.Unlock()
+	readyPipe = os.NewFile(uintptr(fd), "ready")
+}
+
+// startHandover starts executable with args, passing all active listeners
+// as LISTEN_FDS, and waits up to timeout for the new process to accept
+// connections on all of them. A process which doesn't get ready in time is
+// killed, so the listeners stay with this process.
+func startHandover(executable string, args []string, timeout time.Duration) (*os.Process, error) {
+	files, err := activeListenerFiles()
+	if err != nil {
+		return nil, err
+	}
+	// The new process owns its own copies once it is started.
+	defer closeFiles(files)
+
+	if len(files) == 0 {
+		return nil, errors.New("no listeners to hand over")
+	}
+
+	readyReader, readyWriter, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer readyReader.Close()
+
+	env := slices.DeleteFunc(os.Environ(), func(variable string) bool {
+		name, _, _ := strings.Cut(variable, "=")
+		return name == listenFDsEnv || name == listenPIDEnv || name == listenFDNamesEnv || name == readyFDEnv
+	})
+
+	cmd := exec.Command(executable, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = append(files, readyWriter)
+	cmd.Env = append(env,
+		fmt.Sprintf("%s=%d", listenFDsEnv, len(files)),
+		fmt.Sprintf("%s=%d", readyFDEnv, listenFDsStart+len(files)),
+	)
+
+	err = cmd.Start()
+	// Only the new process may hold the write end, so reading fails once it
+	// exited.
+	_ = readyWriter.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := waitReady(readyReader, timeout); err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return nil, fmt.Errorf("new process %d: %w", cmd.Process.Pid, err)
+	}
+
+	// The socket files are used by the new process from now on.
+	for _, listener := range activeListeners() {
+		if unixListener, ok := listener.Listener.(*net.UnixListener); ok {
+			unixListener.SetUnlinkOnClose(false)
+		}
+	}
+	return cmd.Process, nil
+}
+
+// waitReady waits up to timeout for the new process to write to the ready
+// pipe.
+func waitReady(readyReader *os.File, timeout time.Duration) error {
+	if err := readyReader.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+
+	n, err := readyReader.Read(make([]byte, 1))
+	switch {
+	case n > 0:
+		return nil
+	case errors.Is(err, io.EOF):
+		// All write ends are closed once the new process exited.
+		return errors.New("exited before accepting connections")
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		return fmt.Errorf("not accepting connections after %s", timeout)
+	default:
+		return err
+	}
+}
@@ -0,0 +1,128 @@
+//go:build unix
+
+package httpserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// handoverTestAddrEnv passes the listener address to the child process of
+// TestHandover.
+const handoverTestAddrEnv = "HTTPSERVER_HANDOVER_TEST_ADDR"
+
+// TestHandover verifies a new process serves requests on the listener
+// passed by startHandover.
+//
+// The test passes all active listeners of the process, so it must not run in
+// parallel.
+func TestHandover(t *testing.T) {
+	listener, err := listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() {
+		_ = listener.Close()
+	}()
+
+	t.Setenv(handoverTestAddrEnv, listener.Addr().String())
+	process, err := startHandover(os.Args[0], []string{"-test.run=^TestHandoverChild$"}, 10*time.Second)
+	require.NoError(t, err)
+
+	// This process never accepts, so the request is served by the child.
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get("http://" + listener.Addr().String() + "/")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "child", string(body))
+
+	state, err := process.Wait()
+	require.NoError(t, err)
+	assert.True(t, state.Success())
+}
+
+// TestHandoverNotReady verifies a handover fails when the new process exits
+// or doesn't accept connections in time.
+func TestHandoverNotReady(t *testing.T) {
+	t.Parallel()
+
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("sleep is not available")
+	}
+
+	listener, err := listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+
+	tests := []struct {
+		// name describes the test case.
+		name string
+		// args are passed to sleep.
+		args []string
+		// wantErr is the expected error message suffix.
+		wantErr string
+	}{
+		{name: "exits", args: []string{"0"}, wantErr: "exited before accepting connections"},
+		{name: "timeout", args: []string{"10"}, wantErr: "not accepting connections after 200ms"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			started := time.Now()
+			_, err := startHandover(sleep, tt.args, 200*time.Millisecond)
+			require.Error(t, err)
+			assert.True(t, strings.HasSuffix(err.Error(), tt.wantErr), err.Error())
+			assert.Less(t, time.Since(started), 5*time.Second)
+		})
+	}
+}
+
+// TestHandoverChild is run as the child process of TestHandover. It serves a
+// single request on the inherited listener and exits.
+func TestHandoverChild(t *testing.T) {
+	addr := os.Getenv(handoverTestAddrEnv)
+	if len(addr) == 0 {
+		t.Skip("only run as child process of TestHandover")
+	}
+
+	// The test binary must not print its result into the parent's output.
+	listener, err := listen("tcp", addr)
+	if err != nil {
+		os.Exit(1)
+	}
+
+	served := make(chan struct{})
+	server := &http.Server{Handler: http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		_, _ = writer.Write([]byte("child"))
+		close(served)
+	})}
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	select {
+	case <-served:
+	case <-time.After(10 * time.Second):
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = server.Shutdown(ctx)
+	os.Exit(0)
+}
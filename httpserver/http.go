@@ -3,13 +3,14 @@ package httpserver
 import (
 	"context"
 	"errors"
+	"net"
 	"net/http"
 	"slices"
 	"time"
 
 	golog "log"
 
-	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog"
 	"github.com/trivago/go-bootstrap/v2/logging"
 )
 
@@ -18,6 +19,8 @@ const (
 	healthPath = "/healthz"
 	// readyPath is the readiness probe endpoint.
 	readyPath = "/readyz"
+	// startupPath is the startup probe endpoint.
+	startupPath = "/startupz"
 )
 
 // HTTPServer wraps net/http.Server and exposes the shared Server lifecycle.
@@ -25,14 +28,38 @@ type HTTPServer struct {
 	// Server is the underlying net/http server. Callers may configure
 	// additional fields before Listen.
 	Server *http.Server
+
+	// Management is the server for probes and admin endpoints when a
+	// management port is configured, nil otherwise. It is started and
+	// stopped together with Server.
+	Management *http.Server
+
+	// state tracks the startup and readiness reported by the probes.
+	state *lifecycle
+
+	// cert is the TLS certificate handler, nil when TLS is disabled.
+	cert *fileBasedCert
+
+	// network is the network of Server.Addr, "tcp" when empty.
+	network string
+
+	// listener is the pre-bound listener to serve on, if any.
+	listener net.Listener
+
+	// bound records the listen address and when serving started.
+	bound binding
+
+	// managementBound records the listen address of Management and when it
+	// started serving.
+	managementBound binding
 }
 
 // defaultDisableAccessLogFor is the access-log exclusion list used by the
 // convenience constructors.
-var defaultDisableAccessLogFor = []string{healthPath, readyPath}
+var defaultDisableAccessLogFor = []string{healthPath, readyPath, startupPath}
 
 // New creates a net/http server with the given port, probe checks, and
-// handler. Access logs for /healthz and /readyz are disabled by default.
+// handler. Access logs for the probes are disabled by default.
 func New(port int, health, ready Check, handler http.Handler) (*HTTPServer, error) {
 	return NewWithConfig(Config{
 		Port:                port,
@@ -48,69 +75,141 @@ func New(port int, health, ready Check, handler http.Handler) (*HTTPServer, erro
 func NewWithConfig(config Config, handler http.Handler) (*HTTPServer, error) {
 	syncLogThresholds()
 
-	tlsConfig, err := buildTLSConfig(config)
+	tlsConfig, cert, err := buildTLSConfig(config)
 	if err != nil {
 		return nil, err
 	}
 
-	wrapped := wrapHTTPHandler(config, handler)
+	debug, err := newDebugHandler(config.Debug, config.ManagementPort <= 0)
+	if err != nil {
+		return nil, err
+	}
+	warnLogLevelOnMainPort(config.LogLevelPath, config.ManagementPort <= 0)
+
+	state := newLifecycle(config)
+	wrapped := wrapHTTPHandler(config, state, handler)
+	if config.ManagementPort <= 0 {
+		wrapped = withDebugHTTP(debug, wrapped)
+	}
+
+	server := &http.Server{
+		Addr:      resolveAddr(config),
+		Handler:   wrapped,
+		ErrorLog:  newErrorLog(config.ErrorLogRules),
+		TLSConfig: tlsConfig,
+	}
+	applyHTTPLimits(server, config.Limits)
 
 	return &HTTPServer{
-		Server: &http.Server{
-			Addr:      resolveAddr(config),
-			Handler:   wrapped,
-			ErrorLog:  golog.New(logging.ErrorLogWriter{}, "", 0),
-			TLSConfig: tlsConfig,
-		},
+		Server:     server,
+		Management: newManagementServer(config, newManagementHandler(config, state, debug)),
+		state:      state,
+		cert:       cert,
+		network:    resolveNetwork(config, "tcp"),
+		listener:   config.Listener,
 	}, nil
 }
 
-// ListenAndServe starts the server and blocks until it stops. Expected
-// shutdown results are normalized to a nil error.
+// newErrorLog creates the logger for errors of the net/http server. The
+// level of each line is detected from its content through rules, so that
+// routine noise like failed TLS handshakes isn't logged as an error. When
+// rules is nil, logging.DefaultLevelRules are used.
+func newErrorLog(rules []logging.LevelRule) *golog.Logger {
+	writer := logging.NewClassifyingWriter(zerolog.ErrorLevel)
+	if rules != nil {
+		writer.Rules = rules
+	}
+	writer.Log = serverLog.WithLevel
+	return golog.New(writer, "", 0)
+}
+
+// ListenAndServe starts the server and the management server, if any, and
+// blocks until both stop. Expected shutdown results are normalized to a nil
+// error.
 func (s *HTTPServer) ListenAndServe() error {
-	var err error
+	return serveWithManagement(s.Management, &s.managementBound, s.listenAndServe, s.Server.Shutdown)
+}
+
+// listenAndServe starts the main server and blocks until it stops. A
+// pre-bound or inherited listener is used when available.
+func (s *HTTPServer) listenAndServe() error {
+	network := s.network
+	if len(network) == 0 {
+		network = "tcp"
+	}
+
+	listener, err := serveListener(s.listener, network, s.Server.Addr)
+	if err != nil {
+		return err
+	}
+	listener = s.bound.bind(listener)
+
 	if s.Server.TLSConfig != nil {
-		err = s.Server.ListenAndServeTLS("", "")
+		err = s.Server.ServeTLS(listener, "", "")
 	} else {
-		err = s.Server.ListenAndServe()
+		err = s.Server.Serve(listener)
 	}
 
 	if err == nil || errors.Is(err, http.ErrServerClosed) {
 		if errors.Is(err, http.ErrServerClosed) {
-			log.Warn().Msg("HTTP server was instructed to close")
+			serverLog.Warn().Msg("HTTP server was instructed to close")
 		}
 		return nil
 	}
 	return err
 }
 
-// Shutdown gracefully stops the underlying net/http server.
+// Shutdown gracefully stops the underlying net/http server, then the
+// management server, if any.
 func (s *HTTPServer) Shutdown(ctx context.Context) error {
-	return s.Server.Shutdown(ctx)
+	return shutdownWithManagement(ctx, s.Management, s.Server.Shutdown)
 }
 
 // wrapHTTPHandler installs recovery, access logging, and probe routes around
-// the application handler.
-func wrapHTTPHandler(config Config, handler http.Handler) http.Handler {
+// the application handler. Probes are left out when a management port is
+// configured.
+func wrapHTTPHandler(config Config, state *lifecycle, handler http.Handler) http.Handler {
 	if handler == nil {
 		handler = http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
 	}
 
+	logLevel := logLevelHTTP(config.LogLevelTTL)
+	health := probeHTTP("healthz", withDefaultCheck(config.HealthChecks, config.Health), config.CheckTimeout)
+	ready := probeHTTP("readyz", state.readyChecks(withDefaultCheck(config.ReadyChecks, config.Ready)), config.CheckTimeout)
+	startup := probeHTTP("startupz", state.startupChecks(withDefaultCheck(config.StartupChecks, config.Startup)), config.CheckTimeout)
+
+	// Probes and admin endpoints are not limited, so they stay available
+	// under load.
+	limited := limitRequestsHTTP(limitOrDefault(config.Limits.Concurrency, 0), handler)
+
 	withProbes := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if config.ManagementPort > 0 {
+			// Probes and admin endpoints are served by the management server.
+			limited.ServeHTTP(writer, request)
+			return
+		}
+		if len(config.LogLevelPath) > 0 && request.URL.Path == config.LogLevelPath {
+			logLevel(writer, request)
+			return
+		}
 		if request.Method == http.MethodGet {
 			switch request.URL.Path {
 			case healthPath:
-				writer.WriteHeader(probeStatus(request.Context(), config.Health))
+				health(writer, request)
 				return
 			case readyPath:
-				writer.WriteHeader(probeStatus(request.Context(), config.Ready))
+				ready(writer, request)
+				return
+			case startupPath:
+				startup(writer, request)
 				return
 			}
 		}
-		handler.ServeHTTP(writer, request)
+		limited.ServeHTTP(writer, request)
 	})
 
-	withRecovery := recoverHTTP(withProbes)
+	withLimit := limitBodyHTTP(config.Limits.maxRequestBodySize(), withProbes)
+	withRecovery := recoverHTTP(config.PanicHandler, withLimit)
 	return accessLogHTTP(config.DisableAccessLogFor, withRecovery)
 }
 
@@ -173,16 +272,3 @@ func accessLogHTTP(ignorePaths []string, next http.Handler) http.Handler {
 		)
 	})
 }
-
-// recoverHTTP converts panics into HTTP 500 responses.
-func recoverHTTP(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
-		defer func() {
-			if recovered := recover(); recovered != nil {
-				log.Error().Interface("panic", recovered).Msg("Recovered from panic")
-				http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-			}
-		}()
-		next.ServeHTTP(writer, request)
-	})
-}
@@ -0,0 +1,211 @@
+package httpserver
+
+import (
+	"math"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	// defaultReadHeaderTimeout bounds reading the request headers when no
+	// timeout is configured.
+	defaultReadHeaderTimeout = 10 * time.Second
+	// defaultReadTimeout bounds reading the entire request when no timeout
+	// is configured.
+	defaultReadTimeout = time.Minute
+	// defaultIdleTimeout bounds waiting for the next request on a keep-alive
+	// connection when no timeout is configured.
+	defaultIdleTimeout = 2 * time.Minute
+	// defaultMaxRequestBodySize limits request bodies when no limit is
+	// configured. It matches the fasthttp default.
+	defaultMaxRequestBodySize = fasthttp.DefaultMaxRequestBodySize
+)
+
+// LimitsConfig defines timeouts and size limits protecting the server
+// against slow or misbehaving clients. They are applied to net/http and
+// fasthttp servers alike, including the management server. Zero values
+// select the defaults, negative values disable a timeout or limit.
+type LimitsConfig struct {
+	// ReadHeaderTimeout bounds reading the request headers, e.g. against
+	// slowloris attacks. fasthttp only enforces it when ReadTimeout is
+	// enabled, reading the body within ReadTimeout after the headers.
+	// Defaults to 10 seconds.
+	ReadHeaderTimeout time.Duration
+
+	// ReadTimeout bounds reading the entire request, including the body.
+	// Defaults to 1 minute.
+	ReadTimeout time.Duration
+
+	// WriteTimeout bounds writing the response. net/http starts it once the
+	// request headers are read, fasthttp once the handler returned.
+	// When zero, writing is not bounded, e.g. for streaming responses.
+	WriteTimeout time.Duration
+
+	// IdleTimeout bounds waiting for the next request on a keep-alive
+	// connection.
+	// Defaults to 2 minutes.
+	IdleTimeout time.Duration
+
+	// MaxHeaderBytes limits the size of the request line and headers. For
+	// fasthttp, it sets the read buffer size of each connection.
+	// Defaults to 1 MiB for net/http and 4 KiB for fasthttp.
+	MaxHeaderBytes int
+
+	// MaxRequestBodySize limits the size of request bodies, also on the
+	// management server. Requests announcing a larger body are rejected
+	// with 413 Request Entity Too Large. For net/http, reading a larger
+	// chunked body fails.
+	// Defaults to 4 MiB.
+	MaxRequestBodySize int
+
+	// Concurrency limits the number of requests served at the same time by
+	// net/http and Gin, and the number of connections served by fasthttp.
+	// Further requests are rejected with 503 Service Unavailable. It doesn't
+	// apply to probes and admin endpoints of net/http and Gin, nor to the
+	// management server. fasthttp limits connections before routing, so
+	// set ManagementPort to keep its probes available under load.
+	// When zero, net/http is unlimited and fasthttp allows 262144.
+	Concurrency int
+}
+
+// limitOrDefault returns fallback when value is zero, and zero when value is
+// negative, i.e. disabled.
+func limitOrDefault[T time.Duration | int](value, fallback T) T {
+	switch {
+	case value < 0:
+		return 0
+	case value == 0:
+		return fallback
+	default:
+		return value
+	}
+}
+
+// maxRequestBodySize returns the body size limit, or zero when disabled.
+func (limits LimitsConfig) maxRequestBodySize() int {
+	return limitOrDefault(limits.MaxRequestBodySize, defaultMaxRequestBodySize)
+}
+
+// applyHTTPLimits configures the timeouts and header limit of server. The
+// body size and concurrency are limited by the handler.
+func applyHTTPLimits(server *http.Server, limits LimitsConfig) {
+	server.ReadHeaderTimeout = limitOrDefault(limits.ReadHeaderTimeout, defaultReadHeaderTimeout)
+	server.ReadTimeout = limitOrDefault(limits.ReadTimeout, defaultReadTimeout)
+	server.WriteTimeout = limitOrDefault(limits.WriteTimeout, 0)
+	server.IdleTimeout = limitOrDefault(limits.IdleTimeout, defaultIdleTimeout)
+	server.MaxHeaderBytes = limitOrDefault(limits.MaxHeaderBytes, http.DefaultMaxHeaderBytes)
+}
+
+// applyFastHTTPLimits configures the timeouts and limits of server. As
+// fasthttp has no header timeout, the read deadline is extended to
+// ReadTimeout once the headers are received.
+func applyFastHTTPLimits(server *fasthttp.Server, limits LimitsConfig) {
+	readTimeout := limitOrDefault(limits.ReadTimeout, defaultReadTimeout)
+	headerTimeout := limitOrDefault(limits.ReadHeaderTimeout, defaultReadHeaderTimeout)
+
+	server.ReadTimeout = readTimeout
+	if readTimeout > 0 && headerTimeout > 0 && headerTimeout < readTimeout {
+		server.ReadTimeout = headerTimeout
+		server.HeaderReceived = func(*fasthttp.RequestHeader) fasthttp.RequestConfig {
+			return fasthttp.RequestConfig{ReadTimeout: readTimeout}
+		}
+	}
+
+	server.WriteTimeout = limitOrDefault(limits.WriteTimeout, 0)
+	server.IdleTimeout = limitOrDefault(limits.IdleTimeout, defaultIdleTimeout)
+	server.ReadBufferSize = limitOrDefault(limits.MaxHeaderBytes, 0)
+	server.Concurrency = limitOrDefault(limits.Concurrency, 0)
+
+	server.MaxRequestBodySize = limits.maxRequestBodySize()
+	if server.MaxRequestBodySize == 0 {
+		// fasthttp applies its default to zero.
+		server.MaxRequestBodySize = math.MaxInt
+	}
+}
+
+// limitBodyHTTP rejects requests announcing a body larger than maxSize and
+// limits reading the body to maxSize. No limit is applied when maxSize is
+// zero.
+func limitBodyHTTP(maxSize int, next http.Handler) http.Handler {
+	if maxSize <= 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.ContentLength > int64(maxSize) {
+			status := http.StatusRequestEntityTooLarge
+			http.Error(writer, http.StatusText(status), status)
+			return
+		}
+		request.Body = http.MaxBytesReader(writer, request.Body, int64(maxSize))
+		next.ServeHTTP(writer, request)
+	})
+}
+
+// limitBodyGin is a Gin middleware rejecting requests announcing a body
+// larger than maxSize and limiting reading the body to maxSize.
+func limitBodyGin(maxSize int) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if maxSize <= 0 {
+			ctx.Next()
+			return
+		}
+		if ctx.Request.ContentLength > int64(maxSize) {
+			ctx.AbortWithStatus(http.StatusRequestEntityTooLarge)
+			return
+		}
+		ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, int64(maxSize))
+		ctx.Next()
+	}
+}
+
+// limitRequestsHTTP rejects requests with 503 Service Unavailable while limit
+// requests are served. No limit is applied when limit is zero.
+func limitRequestsHTTP(limit int, next http.Handler) http.Handler {
+	if limit <= 0 {
+		return next
+	}
+
+	inFlight := make(chan struct{}, limit)
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		select {
+		case inFlight <- struct{}{}:
+			defer func() { <-inFlight }()
+			next.ServeHTTP(writer, request)
+		default:
+			status := http.StatusServiceUnavailable
+			http.Error(writer, http.StatusText(status), status)
+		}
+	})
+}
+
+// limitRequestsGin is a Gin middleware rejecting requests with 503 Service
+// Unavailable while limit requests are served. Requests to exemptPaths are
+// never limited.
+func limitRequestsGin(limit int, exemptPaths []string) gin.HandlerFunc {
+	if limit <= 0 {
+		return func(ctx *gin.Context) {
+			ctx.Next()
+		}
+	}
+
+	inFlight := make(chan struct{}, limit)
+	return func(ctx *gin.Context) {
+		if slices.Contains(exemptPaths, ctx.Request.URL.Path) {
+			ctx.Next()
+			return
+		}
+
+		select {
+		case inFlight <- struct{}{}:
+			defer func() { <-inFlight }()
+			ctx.Next()
+		default:
+			ctx.AbortWithStatus(http.StatusServiceUnavailable)
+		}
+	}
+}
@@ -0,0 +1,367 @@
+package httpserver
+
+import (
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+// TestApplyHTTPLimits verifies the defaults and overrides of the net/http
+// timeouts and header limit.
+func TestApplyHTTPLimits(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// name describes the test case.
+		name string
+		// limits is the configuration under test.
+		limits LimitsConfig
+		// want holds the expected limit fields of the server.
+		want *http.Server
+	}{
+		{
+			name: "defaults",
+			want: &http.Server{
+				ReadHeaderTimeout: 10 * time.Second,
+				ReadTimeout:       time.Minute,
+				IdleTimeout:       2 * time.Minute,
+				MaxHeaderBytes:    http.DefaultMaxHeaderBytes,
+			},
+		},
+		{
+			name: "overrides",
+			limits: LimitsConfig{
+				ReadHeaderTimeout: time.Second,
+				ReadTimeout:       2 * time.Second,
+				WriteTimeout:      3 * time.Second,
+				IdleTimeout:       4 * time.Second,
+				MaxHeaderBytes:    8192,
+			},
+			want: &http.Server{
+				ReadHeaderTimeout: time.Second,
+				ReadTimeout:       2 * time.Second,
+				WriteTimeout:      3 * time.Second,
+				IdleTimeout:       4 * time.Second,
+				MaxHeaderBytes:    8192,
+			},
+		},
+		{
+			name: "disabled",
+			limits: LimitsConfig{
+				ReadHeaderTimeout: -1,
+				ReadTimeout:       -1,
+				IdleTimeout:       -1,
+			},
+			want: &http.Server{
+				MaxHeaderBytes: http.DefaultMaxHeaderBytes,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := &http.Server{}
+			applyHTTPLimits(server, tt.limits)
+
+			assert.Equal(t, tt.want.ReadHeaderTimeout, server.ReadHeaderTimeout)
+			assert.Equal(t, tt.want.ReadTimeout, server.ReadTimeout)
+			assert.Equal(t, tt.want.WriteTimeout, server.WriteTimeout)
+			assert.Equal(t, tt.want.IdleTimeout, server.IdleTimeout)
+			assert.Equal(t, tt.want.MaxHeaderBytes, server.MaxHeaderBytes)
+		})
+	}
+}
+
+// TestApplyFastHTTPLimits verifies the mapping of the limits onto fasthttp,
+// including the header timeout emulated through HeaderReceived.
+func TestApplyFastHTTPLimits(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// name describes the test case.
+		name string
+		// limits is the configuration under test.
+		limits LimitsConfig
+		// want holds the expected limit fields of the server.
+		want *fasthttp.Server
+		// wantBodyTimeout is the read timeout set once the headers are
+		// received, zero when HeaderReceived is not set.
+		wantBodyTimeout time.Duration
+	}{
+		{
+			name: "defaults",
+			want: &fasthttp.Server{
+				ReadTimeout:        10 * time.Second,
+				IdleTimeout:        2 * time.Minute,
+				MaxRequestBodySize: fasthttp.DefaultMaxRequestBodySize,
+			},
+			wantBodyTimeout: time.Minute,
+		},
+		{
+			name: "overrides",
+			limits: LimitsConfig{
+				ReadHeaderTimeout:  time.Second,
+				ReadTimeout:        2 * time.Second,
+				WriteTimeout:       3 * time.Second,
+				IdleTimeout:        4 * time.Second,
+				MaxHeaderBytes:     8192,
+				MaxRequestBodySize: 1024,
+				Concurrency:        10,
+			},
+			want: &fasthttp.Server{
+				ReadTimeout:        time.Second,
+				WriteTimeout:       3 * time.Second,
+				IdleTimeout:        4 * time.Second,
+				ReadBufferSize:     8192,
+				MaxRequestBodySize: 1024,
+				Concurrency:        10,
+			},
+			wantBodyTimeout: 2 * time.Second,
+		},
+		{
+			name:   "header timeout exceeds read timeout",
+			limits: LimitsConfig{ReadHeaderTimeout: time.Minute, ReadTimeout: time.Second},
+			want: &fasthttp.Server{
+				ReadTimeout:        time.Second,
+				IdleTimeout:        2 * time.Minute,
+				MaxRequestBodySize: fasthttp.DefaultMaxRequestBodySize,
+			},
+		},
+		{
+			name: "disabled",
+			limits: LimitsConfig{
+				ReadTimeout:        -1,
+				IdleTimeout:        -1,
+				MaxRequestBodySize: -1,
+			},
+			want: &fasthttp.Server{
+				MaxRequestBodySize: math.MaxInt,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := &fasthttp.Server{}
+			applyFastHTTPLimits(server, tt.limits)
+
+			assert.Equal(t, tt.want.ReadTimeout, server.ReadTimeout)
+			assert.Equal(t, tt.want.WriteTimeout, server.WriteTimeout)
+			assert.Equal(t, tt.want.IdleTimeout, server.IdleTimeout)
+			assert.Equal(t, tt.want.ReadBufferSize, server.ReadBufferSize)
+			assert.Equal(t, tt.want.MaxRequestBodySize, server.MaxRequestBodySize)
+			assert.Equal(t, tt.want.Concurrency, server.Concurrency)
+
+			if tt.wantBodyTimeout == 0 {
+				assert.Nil(t, server.HeaderReceived)
+				return
+			}
+			require.NotNil(t, server.HeaderReceived)
+			assert.Equal(t, tt.wantBodyTimeout, server.HeaderReceived(nil).ReadTimeout)
+		})
+	}
+}
+
+// TestRequestBodyLimit verifies oversized request bodies are rejected by
+// the net/http, Gin and management servers.
+func TestRequestBodyLimit(t *testing.T) {
+	t.Parallel()
+
+	// readBody answers with the length of the request body, or 400 when
+	// reading it failed.
+	readBody := func(writer http.ResponseWriter, request *http.Request) {
+		body, err := io.ReadAll(request.Body)
+		if err != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = io.WriteString(writer, strings.Repeat("x", len(body)))
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/upload", readBody)
+
+	limits := LimitsConfig{MaxRequestBodySize: 4}
+	srv, err := NewWithConfig(Config{Limits: limits}, mux)
+	require.NoError(t, err)
+
+	ginSrv, err := NewGinWithConfig(GinConfig{
+		Limits: limits,
+		InitRoutes: func(router *gin.Engine) {
+			router.POST("/upload", gin.WrapF(readBody))
+		},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		// name describes the test case.
+		name string
+		// body is the request body.
+		body string
+		// chunked sends the body without Content-Length.
+		chunked bool
+		// wantStatus is the expected response status.
+		wantStatus int
+	}{
+		{name: "within limit", body: "1234", wantStatus: http.StatusOK},
+		{name: "announced too large", body: "12345", wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "chunked too large", body: "12345", chunked: true, wantStatus: http.StatusBadRequest},
+	}
+
+	managementSrv, err := NewWithConfig(Config{
+		Limits:         limits,
+		ManagementPort: 1,
+		InitManagementRoutes: func(mux *http.ServeMux) {
+			mux.HandleFunc("/upload", readBody)
+		},
+	}, nil)
+	require.NoError(t, err)
+
+	handlers := map[string]http.Handler{
+		"net/http":   srv.Server.Handler,
+		"gin":        ginSrv.Server.Handler,
+		"management": managementSrv.Management.Handler,
+	}
+	for name, handler := range handlers {
+		for _, tt := range tests {
+			name, handler, tt := name, handler, tt
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				t.Parallel()
+
+				var body io.Reader = strings.NewReader(tt.body)
+				if tt.chunked {
+					// Hide the length of the body.
+					body = io.MultiReader(body)
+				}
+				request := httptest.NewRequest(http.MethodPost, "/upload", body)
+				if tt.chunked {
+					request.ContentLength = -1
+				}
+				recorder := httptest.NewRecorder()
+
+				handler.ServeHTTP(recorder, request)
+				assert.Equal(t, tt.wantStatus, recorder.Code)
+			})
+		}
+	}
+}
+
+// TestLimitRequests verifies requests beyond the concurrency limit are
+// rejected by the net/http and Gin servers, while probes and the management
+// server are not limited.
+func TestLimitRequests(t *testing.T) {
+	t.Parallel()
+
+	limits := LimitsConfig{Concurrency: 1}
+
+	tests := []struct {
+		// name describes the test case.
+		name string
+		// newHandler creates the handler serving block at /block.
+		newHandler func(block http.HandlerFunc) (http.Handler, error)
+		// wantLimited expects the second concurrent request to be
+		// rejected.
+		wantLimited bool
+	}{
+		{
+			name: "net/http",
+			newHandler: func(block http.HandlerFunc) (http.Handler, error) {
+				srv, err := NewWithConfig(Config{Limits: limits}, block)
+				if err != nil {
+					return nil, err
+				}
+				return srv.Server.Handler, nil
+			},
+			wantLimited: true,
+		},
+		{
+			name: "gin",
+			newHandler: func(block http.HandlerFunc) (http.Handler, error) {
+				srv, err := NewGinWithConfig(GinConfig{
+					Limits: limits,
+					InitRoutes: func(router *gin.Engine) {
+						router.GET("/block", gin.WrapF(block))
+					},
+				})
+				if err != nil {
+					return nil, err
+				}
+				return srv.Server.Handler, nil
+			},
+			wantLimited: true,
+		},
+		{
+			name: "management",
+			newHandler: func(block http.HandlerFunc) (http.Handler, error) {
+				srv, err := NewWithConfig(Config{
+					Limits:         limits,
+					ManagementPort: 1,
+					InitManagementRoutes: func(mux *http.ServeMux) {
+						mux.HandleFunc("/block", block)
+					},
+				}, nil)
+				if err != nil {
+					return nil, err
+				}
+				return srv.Management.Handler, nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			entered := make(chan struct{})
+			release := make(chan struct{})
+			handler, err := tt.newHandler(func(http.ResponseWriter, *http.Request) {
+				entered <- struct{}{}
+				<-release
+			})
+			require.NoError(t, err)
+
+			// serve sends a request to /block and its response status to
+			// status.
+			serve := func(status chan<- int) {
+				recorder := httptest.NewRecorder()
+				handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/block", nil))
+				status <- recorder.Code
+			}
+
+			first, second := make(chan int, 1), make(chan int, 1)
+			go serve(first)
+			<-entered
+			go serve(second)
+
+			if tt.wantLimited {
+				assert.Equal(t, http.StatusServiceUnavailable, <-second)
+
+				probe := httptest.NewRecorder()
+				handler.ServeHTTP(probe, httptest.NewRequest(http.MethodGet, healthPath, nil))
+				assert.Equal(t, http.StatusOK, probe.Code)
+				close(release)
+			} else {
+				<-entered
+				close(release)
+				assert.Equal(t, http.StatusOK, <-second)
+			}
+			assert.Equal(t, http.StatusOK, <-first)
+		})
+	}
+}
@@ -0,0 +1,460 @@
+package httpserver
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// listenFDsEnv holds the number of inherited listeners, see
+	// sd_listen_fds(3).
+	listenFDsEnv = "LISTEN_FDS"
+	// listenPIDEnv holds the process the listeners were passed to. When
+	// set, listeners are only inherited by the process with this ID.
+	listenPIDEnv = "LISTEN_PID"
+	// listenFDNamesEnv holds the names of the inherited listeners.
+	listenFDNamesEnv = "LISTEN_FDNAMES"
+	// listenFDsStart is the file descriptor of the first inherited
+	// listener.
+	listenFDsStart = 3
+	// readyFDEnv holds the file descriptor of the pipe a process started by
+	// a handover writes to once all inherited listeners accept connections.
+	readyFDEnv = "HTTPSERVER_READY_FD"
+	// handoverReadyTimeout bounds waiting for the new process of a
+	// handover to accept connections.
+	handoverReadyTimeout = 30 * time.Second
+)
+
+var (
+	// listenerGuard protects inherited, active, pendingInherited and
+	// readyPipe.
+	listenerGuard sync.Mutex
+
+	// inheritOnce guards loading the inherited listeners.
+	inheritOnce sync.Once
+
+	// inherited holds the listeners passed by systemd or a parent process
+	// which were not claimed by a server yet.
+	inherited []net.Listener
+
+	// active holds all listeners created through listen or track which are not
+	// closed yet. They are passed to the new process on a handover.
+	active = map[*trackedListener]struct{}{}
+
+	// pendingInherited is the number of inherited listeners which did not
+	// accept connections yet.
+	pendingInherited int
+
+	// readyPipe is written to once all inherited listeners accept
+	// connections, telling the process which started this one that it is
+	// ready. Nil when the process was not started by a handover.
+	readyPipe *os.File
+)
+
+// fileListener is implemented by listeners backed by a file descriptor,
+// e.g. *net.TCPListener and *net.UnixListener.
+type fileListener interface {
+	// File returns a copy of the underlying file descriptor.
+	File() (*os.File, error)
+}
+
+// trackedListener is a listener registered in active until it is closed.
+type trackedListener struct {
+	net.Listener
+	// closeOnce guards unregistering the listener.
+	closeOnce sync.Once
+}
+
+// Close unregisters the listener and closes it.
+func (listener *trackedListener) Close() error {
+	listener.closeOnce.Do(func() {
+		listenerGuard.Lock()
+		delete(active, listener)
+		listenerGuard.Unlock()
+	})
+	return listener.Listener.Close()
+}
+
+// listen returns an inherited listener matching network and addr, or
+// creates a new one. The listener is passed to the new process on a
+// handover until it is closed.
+func listen(network, addr string) (net.Listener, error) {
+	inheritOnce.Do(func() {
+		fds := parseListenFDs(os.Getenv(listenFDsEnv), os.Getenv(listenPIDEnv), os.Getpid())
+		inheritListeners(fds)
+		if len(fds) > 0 {
+			inheritReadyPipe(os.Getenv(readyFDEnv))
+		}
+
+		// Listeners must not be inherited by processes started later on.
+		_ = os.Unsetenv(listenFDsEnv)
+		_ = os.Unsetenv(listenPIDEnv)
+		_ = os.Unsetenv(listenFDNamesEnv)
+		_ = os.Unsetenv(readyFDEnv)
+	})
+
+	if listener := claimInherited(network, addr); listener != nil {
+		return &acceptListener{Listener: track(listener), accepting: inheritedAccepting}, nil
+	}
+
+	if strings.HasPrefix(network, "unix") {
+		if err := removeStaleSocket(network, addr); err != nil {
+			return nil, err
+		}
+	}
+	listener, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return track(listener), nil
+}
+
+// serveListener returns listener when set, so pre-bound listeners are
+// handed over as well. Otherwise a listener is created through listen.
+func serveListener(listener net.Listener, network, addr string) (net.Listener, error) {
+	if listener != nil {
+		return track(listener), nil
+	}
+	return listen(network, addr)
+}
+
+// track registers listener in active until it is closed, so it is passed to
+// the new process on a handover.
+func track(listener net.Listener) net.Listener {
+	if tracked, isTracked := listener.(*trackedListener); isTracked {
+		return tracked
+	}
+
+	tracked := &trackedListener{Listener: listener}
+	listenerGuard.Lock()
+	active[tracked] = struct{}{}
+	listenerGuard.Unlock()
+	return tracked
+}
+
+// removeStaleSocket removes the Unix domain socket at path, which is left
+// behind when a process exits without closing its listener. The socket is
+// only removed when connecting to it is refused. Returns an error wrapping
+// syscall.EADDRINUSE otherwise, so a running server is never replaced.
+// Other files are kept, so binding fails for them.
+func removeStaleSocket(network, path string) error {
+	info, err := os.Stat(path)
+	if err != nil || info.Mode()&os.ModeSocket == 0 {
+		return nil
+	}
+
+	conn, err := net.DialTimeout(network, path, time.Second)
+	if err == nil {
+		_ = conn.Close()
+	}
+	if !errors.Is(err, syscall.ECONNREFUSED) {
+		return fmt.Errorf("listen %s %s: %w", network, path, syscall.EADDRINUSE)
+	}
+
+	if err := os.Remove(path); err != nil {
+		serverLog.Warn().Err(err).Str("path", path).Msg("Failed to remove stale socket")
+	}
+	return nil
+}
+
+// parseListenFDs returns the inherited file descriptors announced through
+// the LISTEN_FDS and LISTEN_PID environment variables. Returns nil when the
+// listeners were passed to another process.
+func parseListenFDs(count, pid string, ownPID int) []int {
+	if len(pid) > 0 && pid != strconv.Itoa(ownPID) {
+		return nil
+	}
+
+	n, err := strconv.Atoi(count)
+	if err != nil || n <= 0 {
+		return nil
+	}
+
+	fds := make([]int, n)
+	for i := range fds {
+		fds[i] = listenFDsStart + i
+	}
+	return fds
+}
+
+// inheritListeners converts the file descriptors into listeners available
+// to listen. File descriptors which are no listeners are skipped.
+func inheritListeners(fds []int) {
+	listenerGuard.Lock()
+	defer listenerGuard.Unlock()
+
+	for _, fd := range fds {
+		file := os.NewFile(uintptr(fd), "listener-"+strconv.Itoa(fd))
+		listener, err := net.FileListener(file)
+		// FileListener duplicates the file descriptor.
+		_ = file.Close()
+		if err != nil {
+			serverLog.Warn().Err(err).Int("fd", fd).Msg("Ignoring inherited file descriptor")
+			continue
+		}
+
+		serverLog.Info().
+			Int("fd", fd).
+			Str("addr", listener.Addr().String()).
+			Msg("Inherited listener")
+		inherited = append(inherited, listener)
+		pendingInherited++
+	}
+}
+
+// inheritedAccepting is called once an inherited listener accepts
+// connections. When all inherited listeners do, readiness is reported
+// through the ready pipe.
+func inheritedAccepting() {
+	listenerGuard.Lock()
+	defer listenerGuard.Unlock()
+
+	pendingInherited--
+	if pendingInherited > 0 || readyPipe == nil {
+		return
+	}
+
+	serverLog.Info().Msg("Accepting connections on all inherited listeners")
+	if _, err := readyPipe.Write([]byte{'\n'}); err != nil {
+		serverLog.Warn().Err(err).Msg("Failed to report readiness")
+	}
+	_ = readyPipe.Close()
+	readyPipe = nil
+}
+
+// claimInherited removes and returns the inherited listener matching network
+// and addr. Returns nil when there is none.
+func claimInherited(network, addr string) net.Listener {
+	listenerGuard.Lock()
+	defer listenerGuard.Unlock()
+
+	for i, listener := range inherited {
+		if matchesAddr(listener.Addr(), network, addr) {
+			inherited = slices.Delete(inherited, i, i+1)
+			return listener
+		}
+	}
+	return nil
+}
+
+// matchesAddr reports whether a listener bound to bound serves network and
+// addr. Unspecified IPs like "0.0.0.0" and "::" are treated as equal.
+func matchesAddr(bound net.Addr, network, addr string) bool {
+	switch bound := bound.(type) {
+	case *net.TCPAddr:
+		if !strings.HasPrefix(network, "tcp") {
+			return false
+		}
+		wanted, err := net.ResolveTCPAddr(network, addr)
+		if err != nil || wanted.Port == 0 || wanted.Port != bound.Port {
+			return false
+		}
+		if wanted.IP == nil || wanted.IP.IsUnspecified() {
+			return bound.IP == nil || bound.IP.IsUnspecified()
+		}
+		return wanted.IP.Equal(bound.IP)
+
+	case *net.UnixAddr:
+		return strings.HasPrefix(network, "unix") && bound.Name == addr
+
+	default:
+		return false
+	}
+}
+
+// activeListeners returns all active listeners, ordered by address.
+func activeListeners() []*trackedListener {
+	listenerGuard.Lock()
+	listeners := make([]*trackedListener, 0, len(active))
+	for listener := range active {
+		listeners = append(listeners, listener)
+	}
+	listenerGuard.Unlock()
+
+	slices.SortFunc(listeners, func(a, b *trackedListener) int {
+		return strings.Compare(a.Addr().String(), b.Addr().String())
+	})
+	return listeners
+}
+
+// activeListenerFiles returns copies of the file descriptors of all active
+// listeners, ordered by address.
+func activeListenerFiles() ([]*os.File, error) {
+	listeners := activeListeners()
+	files := make([]*os.File, 0, len(listeners))
+	for _, listener := range listeners {
+		filer, ok := listener.Listener.(fileListener)
+		if !ok {
+			continue
+		}
+		file, err := filer.File()
+		if err != nil {
+			closeFiles(files)
+			return nil, fmt.Errorf("listener %s: %w", listener.Addr(), err)
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// closeFiles closes all files.
+func closeFiles(files []*os.File) {
+	for _, file := range files {
+		_ = file.Close()
+	}
+}
+
+// handover starts a new process of the current executable with the same
+// arguments, passing all active listeners to it. The new process picks them
+// up through listen. Returns once the new process accepts connections on
+// all listeners.
+func handover() error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	process, err := startHandover(executable, os.Args[1:], handoverReadyTimeout)
+	if err != nil {
+		return err
+	}
+
+	serverLog.Info().Int("pid", process.Pid).Msg("New process is accepting connections")
+	return process.Release()
+}
+
+// binding records the address of a server's listener and signals when the
+// server is accepting connections. The zero value is ready to use.
+type binding struct {
+	// guard protects addr and started.
+	guard sync.Mutex
+	// addr is the address of the latest listener, nil before binding.
+	addr net.Addr
+	// started is closed once the server accepts connections.
+	started chan struct{}
+}
+
+// bind records the address of listener and returns listener wrapped to
+// signal started on its first call to Accept. Serve only accepts once it is
+// ready to be shut down.
+func (bound *binding) bind(listener net.Listener) net.Listener {
+	bound.guard.Lock()
+	bound.addr = listener.Addr()
+	bound.guard.Unlock()
+
+	return &acceptListener{Listener: listener, accepting: bound.markAccepting}
+}
+
+// boundAddr returns the address of the latest listener, nil before binding.
+func (bound *binding) boundAddr() net.Addr {
+	bound.guard.Lock()
+	defer bound.guard.Unlock()
+	return bound.addr
+}
+
+// startedChannel returns the channel closed once the server accepts
+// connections.
+func (bound *binding) startedChannel() <-chan struct{} {
+	bound.guard.Lock()
+	defer bound.guard.Unlock()
+	return bound.channel()
+}
+
+// markAccepting closes started, unless it is closed already.
+func (bound *binding) markAccepting() {
+	bound.guard.Lock()
+	defer bound.guard.Unlock()
+
+	started := bound.channel()
+	select {
+	case <-started:
+	default:
+		close(started)
+	}
+}
+
+// channel returns started, creating it on first use. The caller must hold
+// guard.
+func (bound *binding) channel() chan struct{} {
+	if bound.started == nil {
+		bound.started = make(chan struct{})
+	}
+	return bound.started
+}
+
+// acceptListener calls accepting before the first connection is accepted.
+type acceptListener struct {
+	net.Listener
+	// accepting is called once on the first call to Accept.
+	accepting func()
+	// acceptOnce guards calling accepting.
+	acceptOnce sync.Once
+}
+
+// Accept calls accepting once, then waits for the next connection.
+func (listener *acceptListener) Accept() (net.Conn, error) {
+	listener.acceptOnce.Do(listener.accepting)
+	return listener.Listener.Accept()
+}
+
+// Addr returns the address the server is bound to, e.g. to read back the
+// port chosen for ":0". Returns nil before ListenAndServe bound the
+// listener.
+func (s *HTTPServer) Addr() net.Addr {
+	return s.bound.boundAddr()
+}
+
+// Started returns a channel closed once the server accepts connections.
+// Unlike MarkStarted, it is not related to the startup probe. The management
+// server is not covered, see ManagementStarted.
+func (s *HTTPServer) Started() <-chan struct{} {
+	return s.bound.startedChannel()
+}
+
+// ManagementAddr returns the address the management server is bound to.
+// Returns nil without a management port or before ListenAndServe bound the
+// listener.
+func (s *HTTPServer) ManagementAddr() net.Addr {
+	return s.managementBound.boundAddr()
+}
+
+// ManagementStarted returns a channel closed once the management server
+// accepts connections. It is never closed without a management port.
+func (s *HTTPServer) ManagementStarted() <-chan struct{} {
+	return s.managementBound.startedChannel()
+}
+
+// Addr returns the address the server is bound to, e.g. to read back the
+// port chosen for ":0". Returns nil before ListenAndServe bound the
+// listener.
+func (s *FastHTTPServer) Addr() net.Addr {
+	return s.bound.boundAddr()
+}
+
+// Started returns a channel closed once the server accepts connections.
+// Unlike MarkStarted, it is not related to the startup probe. The management
+// server is not covered, see ManagementStarted.
+func (s *FastHTTPServer) Started() <-chan struct{} {
+	return s.bound.startedChannel()
+}
+
+// ManagementAddr returns the address the management server is bound to.
+// Returns nil without a management port or before ListenAndServe bound the
+// listener.
+func (s *FastHTTPServer) ManagementAddr() net.Addr {
+	return s.managementBound.boundAddr()
+}
+
+// ManagementStarted returns a channel closed once the management server
+// accepts connections. It is never closed without a management port.
+func (s *FastHTTPServer) ManagementStarted() <-chan struct{} {
+	return s.managementBound.startedChannel()
+}
@@ -0,0 +1,302 @@
+package httpserver
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseListenFDs verifies the LISTEN_FDS protocol.
+func TestParseListenFDs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// name identifies the test case.
+		name string
+		// count is the value of LISTEN_FDS.
+		count string
+		// pid is the value of LISTEN_PID.
+		pid string
+		// want are the expected file descriptors.
+		want []int
+	}{
+		{name: "unset"},
+		{name: "invalid", count: "two"},
+		{name: "two listeners", count: "2", want: []int{3, 4}},
+		{name: "own pid", count: "1", pid: "42", want: []int{3}},
+		{name: "other pid", count: "1", pid: "43"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, parseListenFDs(tt.count, tt.pid, 42))
+		})
+	}
+}
+
+// TestMatchesAddr verifies inherited listeners are matched by address.
+func TestMatchesAddr(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// name identifies the test case.
+		name string
+		// bound is the address of the inherited listener.
+		bound net.Addr
+		// network is the requested network.
+		network string
+		// addr is the requested address.
+		addr string
+		// want reports whether the listener should match.
+		want bool
+	}{
+		{
+			name:    "any address",
+			bound:   &net.TCPAddr{IP: net.IPv6unspecified, Port: 8080},
+			network: "tcp",
+			addr:    ":8080",
+			want:    true,
+		},
+		{
+			name:    "any ipv4 address",
+			bound:   &net.TCPAddr{IP: net.IPv4zero, Port: 8080},
+			network: "tcp4",
+			addr:    ":8080",
+			want:    true,
+		},
+		{
+			name:    "other port",
+			bound:   &net.TCPAddr{IP: net.IPv6unspecified, Port: 8080},
+			network: "tcp",
+			addr:    ":9090",
+		},
+		{
+			name:    "same ip",
+			bound:   &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8080},
+			network: "tcp",
+			addr:    "127.0.0.1:8080",
+			want:    true,
+		},
+		{
+			name:    "other ip",
+			bound:   &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8080},
+			network: "tcp",
+			addr:    ":8080",
+		},
+		{
+			name:    "random port",
+			bound:   &net.TCPAddr{IP: net.IPv6unspecified, Port: 8080},
+			network: "tcp",
+			addr:    ":0",
+		},
+		{
+			name:    "unix socket",
+			bound:   &net.UnixAddr{Name: "/run/app.sock", Net: "unix"},
+			network: "unix",
+			addr:    "/run/app.sock",
+			want:    true,
+		},
+		{
+			name:    "network mismatch",
+			bound:   &net.UnixAddr{Name: "/run/app.sock", Net: "unix"},
+			network: "tcp",
+			addr:    "/run/app.sock",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, matchesAddr(tt.bound, tt.network, tt.addr))
+		})
+	}
+}
+
+// TestInheritListeners verifies inherited listeners are claimed by listen.
+func TestInheritListeners(t *testing.T) {
+	t.Parallel()
+
+	original, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() {
+		_ = original.Close()
+	}()
+
+	file, err := original.(*net.TCPListener).File()
+	require.NoError(t, err)
+	defer func() {
+		_ = file.Close()
+	}()
+
+	// inheritListeners takes ownership of the file descriptor.
+	fd, err := syscall.Dup(int(file.Fd()))
+	require.NoError(t, err)
+	inheritListeners([]int{fd})
+
+	listener, err := listen("tcp", original.Addr().String())
+	require.NoError(t, err)
+	assert.Equal(t, original.Addr().String(), listener.Addr().String())
+	assert.Nil(t, claimInherited("tcp", original.Addr().String()))
+
+	files, err := activeListenerFiles()
+	require.NoError(t, err)
+	assert.NotEmpty(t, files)
+	closeFiles(files)
+
+	require.NoError(t, listener.Close())
+	listenerGuard.Lock()
+	assert.NotContains(t, active, listener)
+	listenerGuard.Unlock()
+}
+
+// TestRemoveStaleSocket verifies only sockets refusing connections are
+// removed before listening.
+func TestRemoveStaleSocket(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// name describes the test case.
+		name string
+		// prepare creates the file at path, returning a cleanup function.
+		prepare func(t *testing.T, path string) func()
+		// wantErr expects listening to fail with EADDRINUSE.
+		wantErr bool
+	}{
+		{
+			name: "missing",
+			prepare: func(*testing.T, string) func() {
+				return func() {}
+			},
+		},
+		{
+			name: "stale socket",
+			prepare: func(t *testing.T, path string) func() {
+				stale, err := net.Listen("unix", path)
+				require.NoError(t, err)
+				stale.(*net.UnixListener).SetUnlinkOnClose(false)
+				require.NoError(t, stale.Close())
+				return func() {}
+			},
+		},
+		{
+			name: "live socket",
+			prepare: func(t *testing.T, path string) func() {
+				live, err := net.Listen("unix", path)
+				require.NoError(t, err)
+				return func() {
+					_ = live.Close()
+				}
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := t.TempDir() + "/server.sock"
+			defer tt.prepare(t, path)()
+
+			listener, err := listen("unix", path)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, syscall.EADDRINUSE)
+				_, err := os.Stat(path)
+				assert.NoError(t, err)
+				return
+			}
+			require.NoError(t, err)
+			_ = listener.Close()
+		})
+	}
+}
+
+// TestAddrAndStarted verifies Started is closed once the server accepts
+// connections, with Addr reporting the port chosen for ":0".
+func TestAddrAndStarted(t *testing.T) {
+	t.Parallel()
+
+	// boundServer is implemented by all server types of this package.
+	type boundServer interface {
+		Server
+		Addr() net.Addr
+		Started() <-chan struct{}
+	}
+
+	tests := []struct {
+		// name describes the test case.
+		name string
+		// newServer creates the server listening on a random port.
+		newServer func() (boundServer, error)
+	}{
+		{
+			name: "net/http",
+			newServer: func() (boundServer, error) {
+				return NewWithConfig(Config{ListenAddr: "127.0.0.1:0"}, nil)
+			},
+		},
+		{
+			name: "fasthttp",
+			newServer: func() (boundServer, error) {
+				return NewFastHTTPWithConfig(Config{ListenAddr: "127.0.0.1:0"}, nil)
+			},
+		},
+		{
+			name: "gin",
+			newServer: func() (boundServer, error) {
+				return NewGinWithConfig(GinConfig{ListenAddr: "127.0.0.1:0"})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv, err := tt.newServer()
+			require.NoError(t, err)
+			assert.Nil(t, srv.Addr())
+
+			done := make(chan error, 1)
+			go func() {
+				done <- srv.ListenAndServe()
+			}()
+
+			select {
+			case <-srv.Started():
+			case <-time.After(5 * time.Second):
+				t.Fatal("Started was not closed")
+			}
+
+			addr, ok := srv.Addr().(*net.TCPAddr)
+			require.True(t, ok)
+			assert.NotZero(t, addr.Port)
+
+			response, err := http.Get("http://" + addr.String() + healthPath)
+			require.NoError(t, err)
+			_ = response.Body.Close()
+			assert.Equal(t, http.StatusOK, response.StatusCode)
+
+			// Shutting down right away must stop the server.
+			require.NoError(t, srv.Shutdown(context.Background()))
+			select {
+			case err := <-done:
+				assert.NoError(t, err)
+			case <-time.After(5 * time.Second):
+				t.Fatal("ListenAndServe did not return")
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/trivago/go-bootstrap/v2/logging"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	// logLevelParam is the query parameter holding the new log level.
+	logLevelParam = "level"
+	// logLevelTTLParam is the query parameter holding the duration after
+	// which a log level change is reverted.
+	logLevelTTLParam = "ttl"
+)
+
+// levelOverride tracks the process-wide log level changes done through the
+// log level endpoint.
+var levelOverride = &logLevelOverride{}
+
+// logLevelOverride changes the global log level and optionally reverts the
+// change after a TTL.
+type logLevelOverride struct {
+	// guard protects all fields during concurrent changes.
+	guard sync.Mutex
+	// revertTimer reverts the current change, or is nil when the current
+	// level is permanent.
+	revertTimer *time.Timer
+	// revertTo is the level restored by revertTimer.
+	revertTo string
+	// revertAt is the time revertTimer fires.
+	revertAt time.Time
+	// generation is incremented on every change so that stale timers don't
+	// revert newer changes.
+	generation uint64
+}
+
+// logLevelResponse is the JSON body returned by the log level endpoint.
+type logLevelResponse struct {
+	// Level is the current global log level.
+	Level string `json:"level"`
+	// RevertTo is the level restored after RevertAt, if a TTL is active.
+	RevertTo string `json:"revertTo,omitempty"`
+	// RevertAt is the time the current level is reverted, if a TTL is
+	// active.
+	RevertAt *time.Time `json:"revertAt,omitempty"`
+}
+
+// set changes the global log level. A ttl greater than zero reverts the
+// change after the given duration. Consecutive changes with a TTL revert to
+// the level that was active before the first of them.
+func (override *logLevelOverride) set(level string, ttl time.Duration) error {
+	override.guard.Lock()
+	defer override.guard.Unlock()
+
+	previous := logging.GetLogLevel().String()
+	if override.revertTimer != nil {
+		previous = override.revertTo
+	}
+
+	if err := logging.SetLogLevel(level); err != nil {
+		return err
+	}
+	syncLogThresholds()
+
+	override.generation++
+	if override.revertTimer != nil {
+		override.revertTimer.Stop()
+		override.revertTimer = nil
+	}
+
+	event := serverLog.Warn().Str("level", logging.GetLogLevel().String())
+	if ttl > 0 {
+		generation := override.generation
+		override.revertTo = previous
+		override.revertAt = time.Now().Add(ttl)
+		override.revertTimer = time.AfterFunc(ttl, func() {
+			override.revert(generation)
+		})
+		event = event.Str("revertTo", previous).Str("ttl", ttl.String())
+	}
+	event.Msg("Log level changed")
+
+	return nil
+}
+
+// revert restores the level active before a change with TTL, unless a newer
+// change happened in the meantime.
+func (override *logLevelOverride) revert(generation uint64) {
+	override.guard.Lock()
+	defer override.guard.Unlock()
+
+	if generation != override.generation || override.revertTimer == nil {
+		return
+	}
+
+	override.revertTimer = nil
+	if err := logging.SetLogLevel(override.revertTo); err != nil {
+		serverLog.Error().Err(err).Msg("Failed to revert log level")
+		return
+	}
+	syncLogThresholds()
+
+	serverLog.Warn().Str("level", logging.GetLogLevel().String()).Msg("Log level reverted")
+}
+
+// status returns the current log level and pending revert.
+func (override *logLevelOverride) status() logLevelResponse {
+	override.guard.Lock()
+	defer override.guard.Unlock()
+
+	response := logLevelResponse{Level: logging.GetLogLevel().String()}
+	if override.revertTimer != nil {
+		revertAt := override.revertAt
+		response.RevertTo = override.revertTo
+		response.RevertAt = &revertAt
+	}
+	return response
+}
+
+// serveLogLevel implements the log level endpoint independent of the server
+// type. GET returns the current level, PUT and POST change it. The returned
+// body is JSON encoded.
+func serveLogLevel(method, level, ttl string, defaultTTL time.Duration) (int, []byte) {
+	switch method {
+	case http.MethodGet:
+		// Only report the current status.
+
+	case http.MethodPut, http.MethodPost:
+		revertAfter := defaultTTL
+		if len(ttl) > 0 {
+			parsed, err := time.ParseDuration(ttl)
+			if err != nil || parsed < 0 {
+				return logLevelError(http.StatusBadRequest, "invalid ttl")
+			}
+			revertAfter = parsed
+		}
+
+		if err := levelOverride.set(level, revertAfter); err != nil {
+			return logLevelError(http.StatusBadRequest, err.Error())
+		}
+
+	default:
+		return logLevelError(http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+	}
+
+	body, err := json.Marshal(levelOverride.status())
+	if err != nil {
+		return logLevelError(http.StatusInternalServerError, err.Error())
+	}
+	return http.StatusOK, body
+}
+
+// logLevelError returns a JSON encoded error body for the log level
+// endpoint.
+func logLevelError(status int, message string) (int, []byte) {
+	body, _ := json.Marshal(map[string]string{"error": message})
+	return status, body
+}
+
+// logLevelHTTP returns a net/http handler for the log level endpoint.
+func logLevelHTTP(defaultTTL time.Duration) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		query := request.URL.Query()
+		status, body := serveLogLevel(
+			request.Method,
+			query.Get(logLevelParam),
+			query.Get(logLevelTTLParam),
+			defaultTTL,
+		)
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(status)
+		_, _ = writer.Write(body)
+	}
+}
+
+// logLevelFastHTTP returns a fasthttp handler for the log level endpoint.
+func logLevelFastHTTP(defaultTTL time.Duration) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		status, body := serveLogLevel(
+			string(ctx.Method()),
+			string(ctx.QueryArgs().Peek(logLevelParam)),
+			string(ctx.QueryArgs().Peek(logLevelTTLParam)),
+			defaultTTL,
+		)
+
+		ctx.SetContentType("application/json")
+		ctx.SetStatusCode(status)
+		ctx.SetBody(body)
+	}
+}
+
+// logLevelGin returns a Gin handler for the log level endpoint.
+func logLevelGin(defaultTTL time.Duration) gin.HandlerFunc {
+	return gin.WrapF(logLevelHTTP(defaultTTL))
+}
+
+// warnLogLevelOnMainPort logs a warning when the log level endpoint at path
+// is served on the main port, where any client can change the log level.
+func warnLogLevelOnMainPort(path string, mainPort bool) {
+	if mainPort && len(path) > 0 {
+		serverLog.Warn().Str("path", path).Msg("Log level endpoint is served on the main port without authentication.")
+	}
+}
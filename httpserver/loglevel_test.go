@@ -0,0 +1,186 @@
+package httpserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+
+	"github.com/trivago/go-bootstrap/v2/logging"
+)
+
+// TestLogLevelEndpoint verifies reading, changing and auto-reverting the
+// global log level through the admin endpoint on all server types. The test
+// modifies the global level and must not run in parallel.
+func TestLogLevelEndpoint(t *testing.T) {
+	previous := zerolog.GlobalLevel()
+	t.Cleanup(func() {
+		zerolog.SetGlobalLevel(previous)
+	})
+
+	httpServer, err := NewWithConfig(Config{LogLevelPath: "/loglevel"}, nil)
+	require.NoError(t, err)
+	httpURL := "http://" + startHTTPServer(t, httpServer).Addr().String()
+
+	ginServer, err := NewGinWithConfig(GinConfig{LogLevelPath: "/loglevel"})
+	require.NoError(t, err)
+	ginURL := "http://" + startHTTPServer(t, ginServer).Addr().String()
+
+	fastServer, err := NewFastHTTPWithConfig(Config{LogLevelPath: "/loglevel"}, nil)
+	require.NoError(t, err)
+	fastClient := startFastHTTPServer(t, fastServer)
+
+	// do sends a request to the log level endpoint of each server type.
+	do := func(t *testing.T, method, query string) []logLevelResponse {
+		t.Helper()
+
+		responses := []logLevelResponse{}
+		for _, baseURL := range []string{httpURL, ginURL} {
+			request, err := http.NewRequest(method, baseURL+"/loglevel"+query, nil)
+			require.NoError(t, err)
+			response, err := http.DefaultClient.Do(request)
+			require.NoError(t, err)
+			require.Equal(t, http.StatusOK, response.StatusCode)
+
+			var body logLevelResponse
+			require.NoError(t, json.NewDecoder(response.Body).Decode(&body))
+			_ = response.Body.Close()
+			responses = append(responses, body)
+		}
+
+		request := fasthttp.AcquireRequest()
+		defer fasthttp.ReleaseRequest(request)
+		response := fasthttp.AcquireResponse()
+		defer fasthttp.ReleaseResponse(response)
+
+		request.Header.SetMethod(method)
+		request.SetRequestURI("http://fasthttp/loglevel" + query)
+		require.NoError(t, fastClient.Do(request, response))
+		require.Equal(t, fasthttp.StatusOK, response.StatusCode())
+
+		var body logLevelResponse
+		require.NoError(t, json.Unmarshal(response.Body(), &body))
+		return append(responses, body)
+	}
+
+	zerolog.SetGlobalLevel(zerolog.ErrorLevel)
+	for _, response := range do(t, http.MethodGet, "") {
+		assert.Equal(t, "error", response.Level)
+		assert.Nil(t, response.RevertAt)
+	}
+
+	for _, response := range do(t, http.MethodPut, "?level=warn") {
+		assert.Equal(t, "warn", response.Level)
+	}
+	assert.Equal(t, zerolog.WarnLevel, zerolog.GlobalLevel())
+
+	for _, response := range do(t, http.MethodPost, "?level=debug&ttl=100ms") {
+		assert.Equal(t, "debug", response.Level)
+		assert.Equal(t, "warn", response.RevertTo)
+		assert.NotNil(t, response.RevertAt)
+	}
+
+	// status waits for a running revert, including its log message.
+	assert.Eventually(t, func() bool {
+		return levelOverride.status().RevertAt == nil && zerolog.GlobalLevel() == zerolog.WarnLevel
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+// TestServeLogLevelErrors verifies invalid requests are rejected without
+// changing the log level.
+func TestServeLogLevelErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// name identifies the test case.
+		name string
+		// method is the HTTP method to send.
+		method string
+		// level is the level query parameter.
+		level string
+		// ttl is the ttl query parameter.
+		ttl string
+		// wantStatus is the expected response status.
+		wantStatus int
+	}{
+		{name: "unknown level", method: http.MethodPut, level: "infp", wantStatus: http.StatusBadRequest},
+		{name: "invalid ttl", method: http.MethodPut, level: "info", ttl: "soon", wantStatus: http.StatusBadRequest},
+		{name: "negative ttl", method: http.MethodPut, level: "info", ttl: "-1s", wantStatus: http.StatusBadRequest},
+		{name: "unsupported method", method: http.MethodDelete, wantStatus: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			status, body := serveLogLevel(tt.method, tt.level, tt.ttl, 0)
+			assert.Equal(t, tt.wantStatus, status)
+			assert.Contains(t, string(body), `"error"`)
+		})
+	}
+}
+
+// TestLogLevelWarning verifies a warning is logged when the log level
+// endpoint is served on the main port. The test modifies the global logger
+// and must not run in parallel.
+func TestLogLevelWarning(t *testing.T) {
+	output := &bytes.Buffer{}
+	require.NoError(t, logging.Configure(logging.Options{Output: output}))
+	previous := logging.GetLogLevel()
+	require.NoError(t, logging.SetLogLevel("warn"))
+	t.Cleanup(func() {
+		require.NoError(t, logging.SetLogLevel(previous.String()))
+		require.NoError(t, logging.Configure(logging.Options{}))
+	})
+
+	tests := []struct {
+		// name describes the test case.
+		name string
+		// config is the server configuration under test.
+		config Config
+		// wantWarning expects a warning to be logged.
+		wantWarning bool
+	}{
+		{name: "main port", config: Config{LogLevelPath: "/loglevel"}, wantWarning: true},
+		{name: "management port", config: Config{LogLevelPath: "/loglevel", ManagementPort: 9090}},
+		{name: "disabled", config: Config{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			constructors := map[string]func() error{
+				"http": func() error {
+					_, err := NewWithConfig(tt.config, nil)
+					return err
+				},
+				"fasthttp": func() error {
+					_, err := NewFastHTTPWithConfig(tt.config, nil)
+					return err
+				},
+				"gin": func() error {
+					_, err := NewGinWithConfig(GinConfig{
+						LogLevelPath:   tt.config.LogLevelPath,
+						ManagementPort: tt.config.ManagementPort,
+					})
+					return err
+				},
+			}
+			for server, construct := range constructors {
+				output.Reset()
+				require.NoError(t, construct(), server)
+
+				if tt.wantWarning {
+					assert.Contains(t, output.String(), "Log level endpoint", server)
+				} else {
+					assert.Empty(t, output.String(), server)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// newManagementServer creates the management server serving handler on the
+// management port. Returns nil when no management port is configured.
+func newManagementServer(config Config, handler http.Handler) *http.Server {
+	if config.ManagementPort <= 0 {
+		return nil
+	}
+
+	server := &http.Server{
+		Addr:     fmt.Sprintf(":%d", config.ManagementPort),
+		Handler:  handler,
+		ErrorLog: newErrorLog(config.ErrorLogRules),
+	}
+	applyHTTPLimits(server, config.Limits)
+	return server
+}
+
+// newManagementHandler creates the management handler of net/http and
+// fasthttp servers, serving the probes, the log level endpoint, the debug
+// endpoints and the routes registered by InitManagementRoutes. Returns nil
+// when no management port is configured.
+func newManagementHandler(config Config, state *lifecycle, debug http.Handler) http.Handler {
+	if config.ManagementPort <= 0 {
+		return nil
+	}
+
+	mux := http.NewServeMux()
+	if config.InitManagementRoutes != nil {
+		config.InitManagementRoutes(mux)
+	}
+
+	// Probes are served by the wrapper, which serves them only without a
+	// management port. Probes must stay available under load.
+	config.ManagementPort = 0
+	config.Limits.Concurrency = 0
+	return withDebugHTTP(debug, wrapHTTPHandler(config, state, mux))
+}
+
+// serveWithManagement runs serve and the management server, if any, until
+// both have stopped. When one of them fails, the other one is stopped as
+// well. The listener of the management server is recorded in bound.
+func serveWithManagement(
+	management *http.Server,
+	bound *binding,
+	serve func() error,
+	shutdown func(ctx context.Context) error,
+) error {
+	if management == nil {
+		return serve()
+	}
+
+	// Binding upfront reports address conflicts before the main server
+	// starts.
+	listener, err := listen("tcp", management.Addr)
+	if err != nil {
+		return fmt.Errorf("management server: %w", err)
+	}
+
+	managementDone := make(chan error, 1)
+	go func() {
+		err := management.Serve(bound.bind(listener))
+		if errors.Is(err, http.ErrServerClosed) {
+			err = nil
+		}
+		if err != nil {
+			serverLog.Error().Err(err).Msg("Management server failed, stopping HTTP server")
+			_ = shutdown(context.Background())
+		}
+		managementDone <- err
+	}()
+
+	err = serve()
+	if err != nil {
+		_ = management.Close()
+	}
+	return errors.Join(err, <-managementDone)
+}
+
+// shutdownWithManagement gracefully stops the main server through shutdown,
+// then the management server, if any. Probes stay available while the main
+// server drains.
+func shutdownWithManagement(
+	ctx context.Context,
+	management *http.Server,
+	shutdown func(ctx context.Context) error,
+) error {
+	err := shutdown(ctx)
+	if management != nil {
+		err = errors.Join(err, management.Shutdown(ctx))
+	}
+	return err
+}
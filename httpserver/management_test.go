@@ -0,0 +1,221 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+// TestManagementRoutes verifies probes and admin endpoints move to the
+// management server when a management port is configured.
+func TestManagementRoutes(t *testing.T) {
+	t.Parallel()
+
+	failing := func(context.Context) error { return errors.New("unhealthy") }
+	initRoutes := func(mux *http.ServeMux) {
+		mux.HandleFunc("/metrics", func(writer http.ResponseWriter, _ *http.Request) {
+			_, _ = writer.Write([]byte("metrics"))
+		})
+	}
+	notFound := http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.WriteHeader(http.StatusNotFound)
+	})
+
+	config := Config{
+		ManagementPort:       9090,
+		InitManagementRoutes: initRoutes,
+		Health:               failing,
+		LogLevelPath:         "/loglevel",
+	}
+
+	srv, err := NewWithConfig(config, notFound)
+	require.NoError(t, err)
+	require.NotNil(t, srv.Management)
+	assert.Equal(t, ":9090", srv.Management.Addr)
+
+	fastSrv, err := NewFastHTTPWithConfig(config, func(ctx *fasthttp.RequestCtx) {
+		ctx.SetStatusCode(fasthttp.StatusNotFound)
+	})
+	require.NoError(t, err)
+	require.NotNil(t, fastSrv.Management)
+
+	ginSrv, err := NewGinWithConfig(GinConfig{
+		ManagementPort: 9090,
+		Health: func(ctx *gin.Context) {
+			ctx.Status(http.StatusServiceUnavailable)
+		},
+		LogLevelPath: "/loglevel",
+		InitManagementRoutes: func(router *gin.Engine) {
+			router.GET("/metrics", func(ctx *gin.Context) {
+				ctx.String(http.StatusOK, "metrics")
+			})
+		},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, ginSrv.Management)
+
+	tests := []struct {
+		// name identifies the test case.
+		name string
+		// path is the request path to send.
+		path string
+		// wantMain is the expected status of the main server.
+		wantMain int
+		// wantManagement is the expected status of the management server.
+		wantManagement int
+	}{
+		{name: "health", path: "/healthz", wantMain: http.StatusNotFound, wantManagement: http.StatusServiceUnavailable},
+		{name: "ready", path: "/readyz", wantMain: http.StatusNotFound, wantManagement: http.StatusOK},
+		{name: "log level", path: "/loglevel", wantMain: http.StatusNotFound, wantManagement: http.StatusOK},
+		{name: "custom route", path: "/metrics", wantMain: http.StatusNotFound, wantManagement: http.StatusOK},
+	}
+
+	serve := func(handler http.Handler, path string) int {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder.Code
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.wantMain, serve(srv.Server.Handler, tt.path), "net/http")
+			assert.Equal(t, tt.wantManagement, serve(srv.Management.Handler, tt.path), "net/http")
+
+			assert.Equal(t, tt.wantMain, serve(ginSrv.Server.Handler, tt.path), "gin")
+			assert.Equal(t, tt.wantManagement, serve(ginSrv.Management.Handler, tt.path), "gin")
+
+			ctx := &fasthttp.RequestCtx{}
+			ctx.Request.SetRequestURI(tt.path)
+			fastSrv.Server.Handler(ctx)
+			assert.Equal(t, tt.wantMain, ctx.Response.StatusCode(), "fasthttp")
+			assert.Equal(t, tt.wantManagement, serve(fastSrv.Management.Handler, tt.path), "fasthttp")
+		})
+	}
+}
+
+// TestManagementDisabled verifies no management server is created without
+// a management port.
+func TestManagementDisabled(t *testing.T) {
+	t.Parallel()
+
+	srv, err := NewWithConfig(Config{
+		InitManagementRoutes: func(*http.ServeMux) {
+			t.Error("management routes must not be initialized")
+		},
+	}, nil)
+	require.NoError(t, err)
+	assert.Nil(t, srv.Management)
+}
+
+// TestManagementLifecycle verifies the management server is started and
+// stopped together with the main server, reporting its own address.
+func TestManagementLifecycle(t *testing.T) {
+	t.Parallel()
+
+	// managedServer is implemented by all server types of this package.
+	type managedServer interface {
+		Server
+		Addr() net.Addr
+		Started() <-chan struct{}
+		ManagementAddr() net.Addr
+		ManagementStarted() <-chan struct{}
+	}
+
+	tests := []struct {
+		// name describes the test case.
+		name string
+		// newServer creates the server with a management server, both
+		// listening on a random port.
+		newServer func() (managedServer, error)
+	}{
+		{
+			name: "net/http",
+			newServer: func() (managedServer, error) {
+				srv, err := NewWithConfig(Config{ListenAddr: "127.0.0.1:0", ManagementPort: 1}, nil)
+				if err == nil {
+					srv.Management.Addr = "127.0.0.1:0"
+				}
+				return srv, err
+			},
+		},
+		{
+			name: "fasthttp",
+			newServer: func() (managedServer, error) {
+				srv, err := NewFastHTTPWithConfig(Config{ListenAddr: "127.0.0.1:0", ManagementPort: 1}, nil)
+				if err == nil {
+					srv.Management.Addr = "127.0.0.1:0"
+				}
+				return srv, err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv, err := tt.newServer()
+			require.NoError(t, err)
+			assert.Nil(t, srv.ManagementAddr())
+
+			done := make(chan error, 1)
+			go func() {
+				done <- srv.ListenAndServe()
+			}()
+
+			for _, started := range []<-chan struct{}{srv.Started(), srv.ManagementStarted()} {
+				select {
+				case <-started:
+				case <-time.After(5 * time.Second):
+					t.Fatal("server did not start")
+				}
+			}
+
+			response, err := http.Get("http://" + srv.ManagementAddr().String() + healthPath)
+			require.NoError(t, err)
+			_ = response.Body.Close()
+			assert.Equal(t, http.StatusOK, response.StatusCode)
+			assert.NotEqual(t, srv.Addr(), srv.ManagementAddr())
+
+			require.NoError(t, srv.Shutdown(context.Background()))
+			select {
+			case err := <-done:
+				assert.NoError(t, err)
+			case <-time.After(5 * time.Second):
+				t.Fatal("ListenAndServe did not return")
+			}
+		})
+	}
+}
+
+// TestManagementBindFailure verifies ListenAndServe fails without starting
+// the main server when the management port is taken.
+func TestManagementBindFailure(t *testing.T) {
+	t.Parallel()
+
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() {
+		_ = taken.Close()
+	}()
+
+	srv, err := NewFastHTTPWithConfig(Config{ManagementPort: 1}, nil)
+	require.NoError(t, err)
+	srv.addr = "127.0.0.1:0"
+	srv.Management.Addr = taken.Addr().String()
+
+	assert.ErrorContains(t, srv.ListenAndServe(), "management server")
+}
@@ -0,0 +1,246 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	// defaultCheckTimeout bounds each check when no timeout is configured.
+	// It matches the default probe timeout of Kubernetes.
+	defaultCheckTimeout = time.Second
+	// defaultCheckName is the name of the Health or Ready check when it is
+	// combined with named checks.
+	defaultCheckName = "default"
+	// verboseParam is the query parameter requesting the result of all
+	// checks instead of only the failed ones.
+	verboseParam = "verbose"
+	// checkStatusOK marks a succeeded check or probe.
+	checkStatusOK = "ok"
+	// checkStatusFailed marks a failed check or probe.
+	checkStatusFailed = "failed"
+)
+
+// Checks is a set of named checks. All checks are run in parallel and each
+// check is bounded by a timeout.
+type Checks map[string]Check
+
+// probeResult is the JSON body of a probe response.
+type probeResult struct {
+	// Status is checkStatusOK when all checks succeeded.
+	Status string `json:"status"`
+	// Checks holds the result of each reported check.
+	Checks map[string]checkResult `json:"checks,omitempty"`
+}
+
+// checkResult is the result of a single check.
+type checkResult struct {
+	// Status is checkStatusOK when the check succeeded.
+	Status string `json:"status"`
+	// Latency is the duration of the check.
+	Latency string `json:"latency"`
+	// Error is the error returned by the check, if any.
+	Error string `json:"error,omitempty"`
+
+	// err is the error returned by the check.
+	err error
+	// latency is the duration of the check.
+	latency time.Duration
+}
+
+// Check runs all checks in parallel with the default timeout of one second
+// per check. All failures are joined into the returned error. This allows
+// using Checks wherever a Check is expected.
+func (checks Checks) Check(ctx context.Context) error {
+	results := runChecks(ctx, checks, defaultCheckTimeout)
+
+	errs := []error{}
+	for _, name := range slices.Sorted(maps.Keys(results)) {
+		if err := results[name].err; err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// withDefaultCheck returns checks including check as defaultCheckName.
+// Returns checks unchanged when check is nil.
+func withDefaultCheck(checks Checks, check Check) Checks {
+	return withCheck(checks, defaultCheckName, check)
+}
+
+// withCheck returns a copy of checks including check as name. Returns checks
+// unchanged when check is nil.
+func withCheck(checks Checks, name string, check Check) Checks {
+	if check == nil {
+		return checks
+	}
+	merged := make(Checks, len(checks)+1)
+	maps.Copy(merged, checks)
+	merged[name] = check
+	return merged
+}
+
+// runChecks runs all checks in parallel, each bounded by timeout. Checks
+// ignoring their context are reported as failed when the timeout expires.
+func runChecks(ctx context.Context, checks Checks, timeout time.Duration) map[string]checkResult {
+	if timeout <= 0 {
+		timeout = defaultCheckTimeout
+	}
+
+	var (
+		guard   sync.Mutex
+		wait    sync.WaitGroup
+		results = make(map[string]checkResult, len(checks))
+	)
+
+	for name, check := range checks {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			result := runCheck(ctx, check, timeout)
+
+			guard.Lock()
+			results[name] = result
+			guard.Unlock()
+		}()
+	}
+
+	wait.Wait()
+	return results
+}
+
+// runCheck runs a single check bounded by timeout. A panicking check is
+// reported as failed.
+func runCheck(ctx context.Context, check Check, timeout time.Duration) checkResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	started := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				done <- fmt.Errorf("panic: %v", recovered)
+			}
+		}()
+		if check == nil {
+			done <- nil
+			return
+		}
+		done <- check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+	}
+	if err == nil {
+		// Checks finishing after the timeout are failed as well.
+		err = ctx.Err()
+	}
+
+	result := checkResult{
+		Status:  checkStatusOK,
+		latency: time.Since(started),
+		err:     err,
+	}
+	result.Latency = result.latency.String()
+	if err != nil {
+		result.Status = checkStatusFailed
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// serveProbe runs checks and returns the status code and JSON body of the
+// probe response. Without verbose, only failed checks are listed. Failed
+// checks are logged.
+func serveProbe(
+	ctx context.Context,
+	probe string,
+	checks Checks,
+	timeout time.Duration,
+	verbose bool,
+) (int, []byte) {
+	results := runChecks(ctx, checks, timeout)
+
+	response := probeResult{
+		Status: checkStatusOK,
+		Checks: make(map[string]checkResult, len(results)),
+	}
+	for name, result := range results {
+		if result.err != nil {
+			response.Status = checkStatusFailed
+			serverLog.Warn().
+				Err(result.err).
+				Str("probe", probe).
+				Str("check", name).
+				Dur("latency", result.latency).
+				Msg("Check failed")
+		}
+		if verbose || result.err != nil {
+			response.Checks[name] = result
+		}
+	}
+
+	status := http.StatusOK
+	if response.Status != checkStatusOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	body, _ := json.Marshal(response)
+	return status, body
+}
+
+// probeHTTP returns a net/http handler for a probe endpoint.
+func probeHTTP(probe string, checks Checks, timeout time.Duration) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		status, body := serveProbe(
+			request.Context(),
+			probe,
+			checks,
+			timeout,
+			request.URL.Query().Has(verboseParam),
+		)
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(status)
+		_, _ = writer.Write(body)
+	}
+}
+
+// probeFastHTTP returns a fasthttp handler for a probe endpoint.
+func probeFastHTTP(probe string, checks Checks, timeout time.Duration) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		// RequestCtx.Done is not safe to watch concurrently with a server
+		// shutdown, so checks are only bounded by their timeout.
+		status, body := serveProbe(
+			context.Background(),
+			probe,
+			checks,
+			timeout,
+			ctx.QueryArgs().Has(verboseParam),
+		)
+
+		ctx.SetContentType("application/json")
+		ctx.SetStatusCode(status)
+		ctx.SetBody(body)
+	}
+}
+
+// probeGin returns a Gin handler for a probe endpoint.
+func probeGin(probe string, checks Checks, timeout time.Duration) gin.HandlerFunc {
+	return gin.WrapF(probeHTTP(probe, checks, timeout))
+}
@@ -0,0 +1,171 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+// failingCheck always reports an error.
+func failingCheck(context.Context) error {
+	return errors.New("connection refused")
+}
+
+// hangingCheck blocks until its context is done, ignoring the cause.
+func hangingCheck(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
+
+// panickingCheck panics instead of returning an error.
+func panickingCheck(context.Context) error {
+	panic("nil map")
+}
+
+// TestServeProbe verifies the status and body of probe responses.
+func TestServeProbe(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// name identifies the test case.
+		name string
+		// checks are the checks of the probe.
+		checks Checks
+		// verbose requests all check results.
+		verbose bool
+		// wantStatus is the expected response status.
+		wantStatus int
+		// wantChecks maps the listed checks to their expected status.
+		wantChecks map[string]string
+	}{
+		{
+			name:       "no checks",
+			wantStatus: http.StatusOK,
+			wantChecks: map[string]string{},
+		},
+		{
+			name:       "all ok",
+			checks:     Checks{"db": CheckOK, "cache": CheckOK},
+			wantStatus: http.StatusOK,
+			wantChecks: map[string]string{},
+		},
+		{
+			name:       "all ok verbose",
+			checks:     Checks{"db": CheckOK, "cache": CheckOK},
+			verbose:    true,
+			wantStatus: http.StatusOK,
+			wantChecks: map[string]string{"db": checkStatusOK, "cache": checkStatusOK},
+		},
+		{
+			name:       "one failed",
+			checks:     Checks{"db": failingCheck, "cache": CheckOK},
+			wantStatus: http.StatusServiceUnavailable,
+			wantChecks: map[string]string{"db": checkStatusFailed},
+		},
+		{
+			name:       "timeout",
+			checks:     Checks{"slow": hangingCheck},
+			wantStatus: http.StatusServiceUnavailable,
+			wantChecks: map[string]string{"slow": checkStatusFailed},
+		},
+		{
+			name:       "panic",
+			checks:     Checks{"broken": panickingCheck, "cache": CheckOK},
+			wantStatus: http.StatusServiceUnavailable,
+			wantChecks: map[string]string{"broken": checkStatusFailed},
+		},
+		{
+			name:       "nested checks",
+			checks:     Checks{"deps": Checks{"db": failingCheck}.Check},
+			wantStatus: http.StatusServiceUnavailable,
+			wantChecks: map[string]string{"deps": checkStatusFailed},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			status, body := serveProbe(context.Background(), "readyz", tt.checks, 20*time.Millisecond, tt.verbose)
+			assert.Equal(t, tt.wantStatus, status)
+
+			var result probeResult
+			require.NoError(t, json.Unmarshal(body, &result))
+
+			wantStatus := checkStatusOK
+			if tt.wantStatus != http.StatusOK {
+				wantStatus = checkStatusFailed
+			}
+			assert.Equal(t, wantStatus, result.Status)
+
+			gotChecks := map[string]string{}
+			for name, check := range result.Checks {
+				gotChecks[name] = check.Status
+				assert.NotEmpty(t, check.Latency)
+				assert.Equal(t, check.Status == checkStatusFailed, len(check.Error) > 0)
+			}
+			assert.Equal(t, tt.wantChecks, gotChecks)
+		})
+	}
+}
+
+// TestChecksCheck verifies Checks can be used as a Check.
+func TestChecksCheck(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, Checks{"a": CheckOK, "b": nil}.Check(context.Background()))
+
+	err := Checks{"a": CheckOK, "b": failingCheck}.Check(context.Background())
+	assert.EqualError(t, err, "b: connection refused")
+}
+
+// TestProbeEndpoints verifies named checks and the legacy check are served
+// by all server types.
+func TestProbeEndpoints(t *testing.T) {
+	t.Parallel()
+
+	config := Config{
+		Ready:       CheckOK,
+		ReadyChecks: Checks{"db": failingCheck},
+	}
+
+	srv, err := NewWithConfig(config, nil)
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	srv.Server.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz?verbose", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	var result probeResult
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+	assert.Len(t, result.Checks, 2)
+	assert.Equal(t, checkStatusOK, result.Checks[defaultCheckName].Status)
+	assert.Equal(t, "connection refused", result.Checks["db"].Error)
+
+	fastSrv, err := NewFastHTTPWithConfig(config, nil)
+	require.NoError(t, err)
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.SetRequestURI("/readyz")
+	fastSrv.Server.Handler(ctx)
+	assert.Equal(t, http.StatusServiceUnavailable, ctx.Response.StatusCode())
+	result = probeResult{}
+	require.NoError(t, json.Unmarshal(ctx.Response.Body(), &result))
+	assert.Len(t, result.Checks, 1)
+
+	ginSrv, err := NewGinWithConfig(GinConfig{HealthChecks: Checks{"db": CheckOK}})
+	require.NoError(t, err)
+	recorder = httptest.NewRecorder()
+	ginSrv.Server.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	result = probeResult{}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+	assert.Equal(t, probeResult{Status: checkStatusOK}, result)
+}
@@ -0,0 +1,261 @@
+package httpserver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	// requestIDHeader is the HTTP header carrying the request ID set by
+	// clients or proxies.
+	requestIDHeader = "X-Request-Id"
+	// reportedErrorEventType marks log entries as Cloud Error Reporting
+	// events, even if they don't contain a recognized stack trace.
+	// See https://cloud.google.com/error-reporting/docs/formatting-error-messages
+	reportedErrorEventType = "type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent"
+)
+
+// PanicInfo describes a panic recovered while serving a request.
+type PanicInfo struct {
+	// Value is the value passed to panic.
+	Value any
+	// Stack is the stack trace of the panicking goroutine.
+	Stack []byte
+	// RequestID is the value of the X-Request-Id header, if any.
+	RequestID string
+	// Method is the HTTP method of the request.
+	Method string
+	// Path is the path of the request URL.
+	Path string
+	// URL is the full request URL.
+	URL string
+	// UserAgent is the value of the User-Agent header, if any.
+	UserAgent string
+	// RemoteIP is the client IP of the request.
+	RemoteIP string
+}
+
+// PanicResponse is the response written after a recovered panic.
+type PanicResponse struct {
+	// Status is the HTTP status code.
+	// Defaults to 500 Internal Server Error when left empty.
+	Status int
+	// ContentType is the value of the Content-Type header.
+	// Defaults to "text/plain; charset=utf-8" when left empty.
+	ContentType string
+	// Body is the response body.
+	// Defaults to the status text when nil.
+	Body []byte
+}
+
+// PanicHandler is called after a panic has been recovered. Use it to notify
+// external systems or to customize the response. The panic is logged with the
+// returned status afterwards. The context is the context of the request.
+type PanicHandler func(ctx context.Context, info PanicInfo) PanicResponse
+
+// httpRequestContext is the httpRequest of a Cloud Error Reporting event.
+type httpRequestContext struct {
+	// info describes the request.
+	info PanicInfo
+	// status is the response status code.
+	status int
+}
+
+// MarshalZerologObject writes the error context as a JSON object.
+func (request httpRequestContext) MarshalZerologObject(event *zerolog.Event) {
+	event.Dict("httpRequest", zerolog.Dict().
+		Str("method", request.info.Method).
+		Str("url", request.info.URL).
+		Str("userAgent", request.info.UserAgent).
+		Str("remoteIp", request.info.RemoteIP).
+		Int("responseStatusCode", request.status))
+}
+
+// handlePanic logs a recovered panic as Cloud Error Reporting event and
+// returns the response to write.
+func handlePanic(ctx context.Context, info PanicInfo, handler PanicHandler) PanicResponse {
+	response := callPanicHandler(ctx, info, handler)
+
+	writePanicReport(serverLog.Error(), info, response.Status)
+	return response
+}
+
+// writePanicReport writes a recovered panic to event in the Cloud Error
+// Reporting format. The message starts like the output of an unrecovered
+// panic, so Error Reporting detects the stack trace.
+func writePanicReport(event *zerolog.Event, info PanicInfo, status int) {
+	event.
+		Str("@type", reportedErrorEventType).
+		Object("context", httpRequestContext{info: info, status: status}).
+		Str("requestId", info.RequestID).
+		Str("method", info.Method).
+		Str("path", info.Path).
+		Msgf("panic: %v\n\n%s", info.Value, info.Stack)
+}
+
+// callPanicHandler calls handler and applies the response defaults. Panics
+// of the handler itself are logged and answered with the defaults.
+func callPanicHandler(ctx context.Context, info PanicInfo, handler PanicHandler) (response PanicResponse) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			serverLog.Error().Interface("panic", recovered).Msg("PanicHandler panicked")
+			response = PanicResponse{}
+		}
+		response = withPanicDefaults(response)
+	}()
+
+	if handler != nil {
+		response = handler(ctx, info)
+	}
+	return response
+}
+
+// withPanicDefaults fills empty fields of response with their defaults.
+func withPanicDefaults(response PanicResponse) PanicResponse {
+	if response.Status == 0 {
+		response.Status = http.StatusInternalServerError
+	}
+	if len(response.ContentType) == 0 {
+		response.ContentType = "text/plain; charset=utf-8"
+	}
+	if response.Body == nil {
+		response.Body = []byte(http.StatusText(response.Status))
+	}
+	return response
+}
+
+// httpPanicInfo returns the PanicInfo for a net/http request.
+func httpPanicInfo(recovered any, request *http.Request, remoteIP string) PanicInfo {
+	scheme := "http"
+	if request.TLS != nil {
+		scheme = "https"
+	}
+
+	return PanicInfo{
+		Value:     recovered,
+		Stack:     debug.Stack(),
+		RequestID: request.Header.Get(requestIDHeader),
+		Method:    request.Method,
+		Path:      request.URL.Path,
+		URL:       fmt.Sprintf("%s://%s%s", scheme, request.Host, request.URL.RequestURI()),
+		UserAgent: request.UserAgent(),
+		RemoteIP:  remoteIP,
+	}
+}
+
+// recoverHTTP converts panics into HTTP 500 responses, or the response of
+// the PanicHandler. http.ErrAbortHandler is re-panicked, so net/http aborts
+// the response as intended.
+func recoverHTTP(handler PanicHandler, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			info := httpPanicInfo(recovered, request, resolveClientIP(
+				request.RemoteAddr,
+				request.Header.Get(forwardedForHeader),
+				request.Header.Get(realIPHeader),
+			))
+			response := handlePanic(request.Context(), info, handler)
+
+			if recorder, ok := writer.(*statusRecorder); ok && recorder.written {
+				// The response has already been started.
+				return
+			}
+			writer.Header().Set("Content-Type", response.ContentType)
+			writer.WriteHeader(response.Status)
+			_, _ = writer.Write(response.Body)
+		}()
+		next.ServeHTTP(writer, request)
+	})
+}
+
+// abortedKey is the user value marking fasthttp requests aborted with
+// http.ErrAbortHandler.
+type abortedKey struct{}
+
+// recoverFastHTTP converts panics into HTTP 500 responses, or the response
+// of the PanicHandler. http.ErrAbortHandler closes the connection without
+// writing a response, like net/http does, as fasthttp has no equivalent.
+func recoverFastHTTP(handler PanicHandler, next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				// The server closes hijacked connections after the hijack
+				// handler returns.
+				ctx.SetUserValue(abortedKey{}, true)
+				ctx.HijackSetNoResponse(true)
+				ctx.Hijack(func(net.Conn) {})
+				return
+			}
+
+			info := PanicInfo{
+				Value:     recovered,
+				Stack:     debug.Stack(),
+				RequestID: string(ctx.Request.Header.Peek(requestIDHeader)),
+				Method:    string(ctx.Method()),
+				Path:      string(ctx.Path()),
+				URL:       ctx.URI().String(),
+				UserAgent: string(ctx.UserAgent()),
+				RemoteIP: resolveClientIP(
+					ctx.RemoteIP().String(),
+					string(ctx.Request.Header.Peek(forwardedForHeader)),
+					string(ctx.Request.Header.Peek(realIPHeader)),
+				),
+			}
+			response := handlePanic(ctx, info, handler)
+
+			ctx.Response.Reset()
+			ctx.SetStatusCode(response.Status)
+			ctx.SetContentType(response.ContentType)
+			ctx.SetBody(response.Body)
+		}()
+		next(ctx)
+	}
+}
+
+// recoverGin is a Gin middleware converting panics into HTTP 500 responses,
+// or the response of the PanicHandler. http.ErrAbortHandler is re-panicked,
+// so net/http aborts the response as intended.
+func recoverGin(handler PanicHandler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			info := httpPanicInfo(recovered, c.Request, c.ClientIP())
+			response := handlePanic(c, info, handler)
+
+			if c.Writer.Written() {
+				// The response has already been started.
+				c.Abort()
+				return
+			}
+			c.Data(response.Status, response.ContentType, response.Body)
+			c.Abort()
+		}()
+		c.Next()
+	}
+}
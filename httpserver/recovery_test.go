@@ -0,0 +1,201 @@
+package httpserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+// teapotPanicHandler answers all panics with 418 and a JSON body.
+func teapotPanicHandler(_ context.Context, info PanicInfo) PanicResponse {
+	return PanicResponse{
+		Status:      http.StatusTeapot,
+		ContentType: "application/json",
+		Body:        []byte(`{"path":"` + info.Path + `"}`),
+	}
+}
+
+// TestPanicResponses verifies the default and customized panic responses
+// of all server types.
+func TestPanicResponses(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// name identifies the test case.
+		name string
+		// handler is the PanicHandler under test.
+		handler PanicHandler
+		// wantStatus is the expected response status.
+		wantStatus int
+		// wantContentType is the expected Content-Type header.
+		wantContentType string
+		// wantBody is the expected response body.
+		wantBody string
+	}{
+		{
+			name:            "default response",
+			wantStatus:      http.StatusInternalServerError,
+			wantContentType: "text/plain; charset=utf-8",
+			wantBody:        "Internal Server Error",
+		},
+		{
+			name:            "custom response",
+			handler:         teapotPanicHandler,
+			wantStatus:      http.StatusTeapot,
+			wantContentType: "application/json",
+			wantBody:        `{"path":"/panic"}`,
+		},
+		{
+			name: "panicking handler",
+			handler: func(context.Context, PanicInfo) PanicResponse {
+				panic("again")
+			},
+			wantStatus:      http.StatusInternalServerError,
+			wantContentType: "text/plain; charset=utf-8",
+			wantBody:        "Internal Server Error",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name+" net/http", func(t *testing.T) {
+			t.Parallel()
+
+			srv, err := NewWithConfig(Config{PanicHandler: tt.handler}, http.HandlerFunc(
+				func(http.ResponseWriter, *http.Request) { panic("boom") },
+			))
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			srv.Server.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+			assert.Equal(t, tt.wantStatus, recorder.Code)
+			assert.Equal(t, tt.wantContentType, recorder.Header().Get("Content-Type"))
+			assert.Equal(t, tt.wantBody, recorder.Body.String())
+		})
+
+		t.Run(tt.name+" gin", func(t *testing.T) {
+			t.Parallel()
+
+			srv, err := NewGinWithConfig(GinConfig{
+				PanicHandler: tt.handler,
+				InitRoutes: func(router *gin.Engine) {
+					router.GET("/panic", func(*gin.Context) { panic("boom") })
+				},
+			})
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			srv.Server.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+			assert.Equal(t, tt.wantStatus, recorder.Code)
+			assert.Equal(t, tt.wantContentType, recorder.Header().Get("Content-Type"))
+			assert.Equal(t, tt.wantBody, recorder.Body.String())
+		})
+
+		t.Run(tt.name+" fasthttp", func(t *testing.T) {
+			t.Parallel()
+
+			srv, err := NewFastHTTPWithConfig(Config{PanicHandler: tt.handler}, func(*fasthttp.RequestCtx) {
+				panic("boom")
+			})
+			require.NoError(t, err)
+
+			ctx := &fasthttp.RequestCtx{}
+			ctx.Request.SetRequestURI("/panic")
+			srv.Server.Handler(ctx)
+
+			assert.Equal(t, tt.wantStatus, ctx.Response.StatusCode())
+			assert.Equal(t, tt.wantContentType, string(ctx.Response.Header.ContentType()))
+			assert.Equal(t, tt.wantBody, string(ctx.Response.Body()))
+		})
+	}
+}
+
+// TestPanicAbortHandler verifies http.ErrAbortHandler is re-panicked by the
+// net/http and Gin servers, and closes the connection of fasthttp servers
+// without a response.
+func TestPanicAbortHandler(t *testing.T) {
+	t.Parallel()
+
+	abort := func(http.ResponseWriter, *http.Request) { panic(http.ErrAbortHandler) }
+
+	srv, err := NewWithConfig(Config{}, http.HandlerFunc(abort))
+	require.NoError(t, err)
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		srv.Server.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+
+	ginSrv, err := NewGinWithConfig(GinConfig{
+		InitRoutes: func(router *gin.Engine) {
+			router.GET("/", gin.WrapF(abort))
+		},
+	})
+	require.NoError(t, err)
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		ginSrv.Server.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+
+	fastSrv, err := NewFastHTTPWithConfig(Config{}, func(*fasthttp.RequestCtx) {
+		panic(http.ErrAbortHandler)
+	})
+	require.NoError(t, err)
+	fastClient := startFastHTTPServer(t, fastSrv)
+
+	request := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(request)
+	response := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(response)
+
+	request.SetRequestURI("http://fasthttp/")
+	assert.Error(t, fastClient.Do(request, response))
+}
+
+// TestWritePanicReport verifies recovered panics are logged as Cloud Error
+// Reporting events.
+func TestWritePanicReport(t *testing.T) {
+	t.Parallel()
+
+	request := httptest.NewRequest(http.MethodPost, "http://example.com/panic?q=1", nil)
+	request.Header.Set(requestIDHeader, "abc")
+	request.Header.Set("User-Agent", "test")
+
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf)
+	writePanicReport(logger.Error(), httpPanicInfo("boom", request, "192.0.2.1"), http.StatusInternalServerError)
+
+	var report struct {
+		Type      string `json:"@type"`
+		Message   string `json:"message"`
+		RequestID string `json:"requestId"`
+		Method    string `json:"method"`
+		Path      string `json:"path"`
+		Context   struct {
+			HTTPRequest map[string]any `json:"httpRequest"`
+		} `json:"context"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &report), buf.String())
+
+	assert.Equal(t, reportedErrorEventType, report.Type)
+	assert.True(t, strings.HasPrefix(report.Message, "panic: boom\n\ngoroutine "), report.Message)
+	assert.Equal(t, "abc", report.RequestID)
+	assert.Equal(t, http.MethodPost, report.Method)
+	assert.Equal(t, "/panic", report.Path)
+	assert.Equal(t, map[string]any{
+		"method":             http.MethodPost,
+		"url":                "http://example.com/panic?q=1",
+		"userAgent":          "test",
+		"remoteIp":           "192.0.2.1",
+		"responseStatusCode": float64(http.StatusInternalServerError),
+	}, report.Context.HTTPRequest)
+}
@@ -3,14 +3,14 @@ package httpserver
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
 	"time"
 
-	"github.com/rs/zerolog/log"
+	"github.com/trivago/go-bootstrap/v2/logging"
 )
 
 const (
@@ -23,8 +23,28 @@ const (
 	// defaultCertCacheDuration is how long a TLS certificate stays cached
 	// before it is reloaded from disk.
 	defaultCertCacheDuration = 7 * 24 * time.Hour
-	// shutdownTimeout bounds the graceful shutdown triggered by Listen.
-	shutdownTimeout = 30 * time.Second
+	// defaultShutdownTimeout bounds the graceful shutdown triggered by
+	// Listen when no timeout is configured.
+	defaultShutdownTimeout = 30 * time.Second
+	// logFlushTimeout bounds writing buffered log lines when Listen returns.
+	logFlushTimeout = 5 * time.Second
+)
+
+var (
+	// serverLog is used for lifecycle, recovery and admin events.
+	serverLog = logging.Component("httpserver")
+	// tlsLog is used for TLS certificate handling.
+	tlsLog = logging.Component("httpserver.tls")
+	// accessLog is used for access log entries.
+	accessLog = logging.Component("httpserver.accesslog")
+)
+
+var (
+	// ErrStartup is returned by Run when the server failed to start or
+	// stopped with an error.
+	ErrStartup = errors.New("server failed")
+	// ErrShutdown is returned by Run when the graceful shutdown failed.
+	ErrShutdown = errors.New("graceful shutdown failed")
 )
 
 // Check reports probe health. A nil Check or a nil error means the probe
@@ -37,6 +57,38 @@ type Config struct {
 	// Defaults to 8080, or 8443 for TLS when left empty.
 	Port int
 
+	// ListenAddr defines the address the server will listen on, e.g.
+	// "127.0.0.1:8080" to bind a single interface or ":0" for a random
+	// port. For the "unix" network, it is the path of the socket file.
+	// Overrides Port when set.
+	ListenAddr string
+
+	// Network defines the network of ListenAddr: "tcp", "tcp4", "tcp6" or
+	// "unix". A stale socket file of a previous process is removed.
+	// Defaults to "tcp", or "tcp4" for fasthttp servers.
+	Network string
+
+	// Listener defines a pre-bound listener to serve on, e.g. a ":0"
+	// listener in tests. Overrides Port, ListenAddr and Network. The
+	// listener is closed when the server shuts down.
+	Listener net.Listener
+
+	// Limits defines timeouts and size limits protecting the server against
+	// slow or misbehaving clients, see LimitsConfig for the defaults.
+	Limits LimitsConfig
+
+	// ManagementPort enables a second, plain HTTP listener on the given port
+	// serving the probes, the LogLevelPath endpoint and InitManagementRoutes.
+	// These endpoints are no longer served on Port. The management server
+	// is started and stopped together with the main server.
+	// When left empty, all endpoints are served on Port.
+	ManagementPort int
+
+	// InitManagementRoutes defines a function that will be called to
+	// register additional routes, e.g. metrics, on the management server.
+	// Only used when ManagementPort is set.
+	InitManagementRoutes func(mux *http.ServeMux)
+
 	// Health defines the check for the /healthz endpoint.
 	// When nil, the endpoint always returns 200 OK.
 	Health Check
@@ -45,6 +97,46 @@ type Config struct {
 	// When nil, the endpoint always returns 200 OK.
 	Ready Check
 
+	// HealthChecks defines named checks for the /healthz endpoint. They are
+	// run in parallel together with Health, which is reported as "default".
+	// The endpoint returns a JSON body listing failed checks, or all checks
+	// when the "verbose" query parameter is set.
+	HealthChecks Checks
+
+	// ReadyChecks defines named checks for the /readyz endpoint. They are
+	// run in parallel together with Ready, which is reported as "default".
+	// The endpoint returns a JSON body listing failed checks, or all checks
+	// when the "verbose" query parameter is set.
+	ReadyChecks Checks
+
+	// Startup defines the check for the /startupz endpoint.
+	// When nil, the endpoint always returns 200 OK.
+	Startup Check
+
+	// StartupChecks defines named checks for the /startupz endpoint. They
+	// are run in parallel together with Startup, see HealthChecks.
+	StartupChecks Checks
+
+	// CheckTimeout bounds the duration of each check.
+	// Defaults to 1 second when left empty.
+	CheckTimeout time.Duration
+
+	// DeferStartup lets /startupz and /readyz fail until MarkStarted or
+	// MarkReady is called. Use it for initialization running after the
+	// listener is up, e.g. migrations.
+	DeferStartup bool
+
+	// DeferReady lets /readyz fail until MarkReady is called. Use it for
+	// warm-up phases, e.g. filling caches, during which the server must not
+	// receive traffic.
+	DeferReady bool
+
+	// DrainDelay defines how long Listen keeps serving with /readyz failing
+	// after a stop signal before the graceful shutdown begins. This gives
+	// load balancers time to stop routing traffic to the server.
+	// When zero, the shutdown begins immediately.
+	DrainDelay time.Duration
+
 	// DisableAccessLogFor defines a list of paths for which the access log
 	// will not be written. The path must match exactly, including case.
 	DisableAccessLogFor []string
@@ -60,6 +152,36 @@ type Config struct {
 	// CertCacheDuration defines how long a certificate will be cached in
 	// memory before it is reloaded from disk. Default duration is 7 days.
 	CertCacheDuration time.Duration
+
+	// LogLevelPath defines the path of an admin endpoint to read and change
+	// the global log level. GET returns the current level, PUT and POST set
+	// it from the "level" query parameter. An optional "ttl" query parameter
+	// reverts the change after the given duration, e.g. "ttl=10m".
+	// The endpoint is not authenticated. It is served on the public port
+	// unless ManagementPort is set, so anyone reaching the server can change
+	// the log level. A warning is logged when it is served on the public port.
+	// When left empty, the endpoint is disabled.
+	LogLevelPath string
+
+	// LogLevelTTL defines after which duration a log level change through
+	// LogLevelPath is reverted when no "ttl" query parameter is given.
+	// When zero, such changes are kept until the next change.
+	LogLevelTTL time.Duration
+
+	// Debug enables pprof and expvar endpoints below /debug/. They are
+	// served on the management port, if configured.
+	Debug DebugConfig
+
+	// PanicHandler is called after a panic in a handler has been recovered.
+	// Use it to notify external systems or to customize the response.
+	// When nil, a 500 Internal Server Error is returned.
+	PanicHandler PanicHandler
+
+	// ErrorLogRules defines the rules detecting the level of the lines the
+	// net/http server writes to its ErrorLog, e.g. TLS handshake errors.
+	// Lines without a matching rule are logged as errors.
+	// When nil, logging.DefaultLevelRules are used.
+	ErrorLogRules []logging.LevelRule
 }
 
 // Server is the shared lifecycle for net/http and fasthttp servers.
@@ -71,6 +193,17 @@ type Server interface {
 	Shutdown(ctx context.Context) error
 }
 
+// Drainer is implemented by servers supporting a drain phase between a stop
+// signal and the graceful shutdown.
+type Drainer interface {
+	// MarkDraining lets the readiness probe fail while the server keeps
+	// serving requests.
+	MarkDraining()
+
+	// DrainDelay returns how long to keep serving after MarkDraining.
+	DrainDelay() time.Duration
+}
+
 // CheckOK is a Check that always reports success.
 func CheckOK(_ context.Context) error {
 	return nil
@@ -78,41 +211,174 @@ func CheckOK(_ context.Context) error {
 
 // Listen starts the given server and blocks until a stop signal like SIGINT,
 // SIGQUIT or SIGTERM is received. Use signalHandler if you need to react on
-// any of these signals. Graceful shutdown is bounded by shutdownTimeout.
-func Listen(srv Server, signalHandler func(os.Signal)) {
-	signalChan := make(chan os.Signal, 1)
-	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+// any of these signals. Listen is a wrapper of Run, see Run for the options.
+// Errors are logged only; use Run to handle them.
+func Listen(srv Server, signalHandler func(os.Signal), opts ...ListenOption) {
+	opts = append([]ListenOption{WithSignalHandler(signalHandler)}, opts...)
+	_ = Run(context.Background(), srv, opts...)
+}
+
+// Run starts the given server and blocks until ctx is done or a stop signal
+// like SIGINT, SIGQUIT or SIGTERM is received. The stop signals can be
+// changed through WithShutdownSignals, while WithSignalAction registers
+// signals running an action like Reload instead. WithHandover enables
+// zero-downtime restarts. Servers implementing Drainer are drained before
+// the graceful shutdown, which is bounded by WithShutdownTimeout. Hooks
+// registered through WithShutdownHook are run by phase, also when the server
+// fails to start. Buffered log lines are flushed before Run returns.
+//
+// Errors of the server are wrapped in ErrStartup, errors of the graceful
+// shutdown in ErrShutdown. Both are joined when both occurred.
+func Run(ctx context.Context, srv Server, opts ...ListenOption) error {
+	defer flushLogs()
+
+	options := newListenOptions(opts)
 
-	// Launch server async, as ListenAndServe is blocking.
+	signals := notifySignals(options)
+	defer signals.stop()
+
+	// Launch server async, as ListenAndServe is blocking. serveErr may only
+	// be read once stopped is closed.
+	var serveErr error
+	stopped := make(chan struct{})
 	go func() {
-		log.Info().Msg("Starting listener")
+		serverLog.Info().Msg("Starting listener")
 
-		if err := srv.ListenAndServe(); err != nil {
-			log.Error().Err(err).Msg("Failed to start HTTP server")
+		if serveErr = listenAndServe(srv, options.shutdownTimeout); serveErr != nil {
+			serverLog.Error().Err(serveErr).Msg("Failed to start HTTP server")
 		}
 
-		log.Info().Msg("Listener exited")
-		signal.Stop(signalChan)
-		close(signalChan)
+		serverLog.Info().Msg("Listener exited")
+		close(stopped)
 	}()
 
-	// React on external OS signals to trigger a shutdown.
-	// If the channel was closed, the server did not start.
-	if sig, isOpen := <-signalChan; isOpen {
-		log.Info().Msgf("Received signal: %s", sig.String())
-		if signalHandler != nil {
-			signalHandler(sig)
-		}
+	// React on external OS signals or ctx to trigger a shutdown.
+	// If the listener exited, the server did not start.
+	var shutdownErr error
+	for {
+		select {
+		case sig := <-signals.actions:
+			runSignalAction(ctx, srv, options, sig)
+			continue
 
-		log.Info().Msg("Stopping HTTP server")
+		case sig := <-signals.handover:
+			serverLog.Info().Msgf("Received signal: %s, handing over listeners", sig.String())
+			if err := handover(); err != nil {
+				serverLog.Error().Err(err).Msg("Listener handover failed, continuing to serve")
+				continue
+			}
+			shutdownErr = shutdown(srv, options, signals.shutdown, stopped)
+
+		case sig := <-signals.shutdown:
+			serverLog.Info().Msgf("Received signal: %s", sig.String())
+			if options.signalHandler != nil {
+				options.signalHandler(sig)
+			}
+			shutdownErr = shutdown(srv, options, signals.shutdown, stopped)
 
-		// This call is blocking and unblocks the server go routine.
-		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
-		defer cancel()
+		case <-ctx.Done():
+			serverLog.Info().Err(context.Cause(ctx)).Msg("Context done")
+			shutdownErr = shutdown(srv, options, signals.shutdown, stopped)
 
-		if err := srv.Shutdown(ctx); err != nil {
-			log.Error().Err(err).Msg("Graceful shutdown failed")
+		case <-stopped:
+			runShutdownHooks(options.hooks, BeforeDrain)
 		}
+		break
+	}
+
+	runShutdownHooks(options.hooks, AfterStop)
+	runShutdownHooks(options.hooks, CloseResources)
+
+	var errs []error
+	select {
+	case <-stopped:
+		if serveErr != nil {
+			errs = append(errs, fmt.Errorf("%w: %w", ErrStartup, serveErr))
+		}
+	default:
+		// The listener did not exit in time, see stop.
+	}
+	if shutdownErr != nil {
+		errs = append(errs, fmt.Errorf("%w: %w", ErrShutdown, shutdownErr))
+	}
+	return errors.Join(errs...)
+}
+
+// shutdown drains srv while running the BeforeDrain hooks and stops it.
+func shutdown(srv Server, options listenOptions, signals <-chan os.Signal, stopped <-chan struct{}) error {
+	drain(srv, signals, stopped, func() {
+		runShutdownHooks(options.hooks, BeforeDrain)
+	})
+	return stop(srv, options.shutdownTimeout, stopped)
+}
+
+// stop gracefully shuts down srv bounded by timeout and waits for its
+// listener to exit.
+func stop(srv Server, timeout time.Duration, stopped <-chan struct{}) error {
+	serverLog.Info().Dur("timeout", timeout).Msg("Stopping HTTP server")
+
+	// This call is blocking and unblocks the server go routine.
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := srv.Shutdown(ctx)
+	if err != nil {
+		serverLog.Error().Err(err).Msg("Graceful shutdown failed")
+	}
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		serverLog.Error().Msg("Listener did not exit in time")
+		err = errors.Join(err, ctx.Err())
+	}
+	return err
+}
+
+// drain marks srv as draining and waits for its drain delay while it keeps
+// serving. during is run after srv was marked, its duration counts towards
+// the delay. Another signal or the server exiting ends the wait early.
+// Servers not implementing Drainer or without a delay are not drained, but
+// during is run nonetheless.
+func drain(srv Server, signals <-chan os.Signal, stopped <-chan struct{}, during func()) {
+	drainer, ok := srv.(Drainer)
+	if !ok || drainer.DrainDelay() <= 0 {
+		during()
+		return
+	}
+
+	delay := drainer.DrainDelay()
+	drainer.MarkDraining()
+	serverLog.Info().Dur("delay", delay).Msg("Draining HTTP server, readiness probe is failing")
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	during()
+
+	select {
+	case <-timer.C:
+		serverLog.Info().Msg("Drain delay passed")
+	case sig := <-signals:
+		serverLog.Warn().Msgf("Received signal: %s, skipping remaining drain delay", sig.String())
+	case <-stopped:
+		serverLog.Warn().Msg("Listener exited while draining")
+	}
+}
+
+// flushLogs writes all log lines buffered by the global logger, reporting
+// lines that were dropped because the buffer was full.
+func flushLogs() {
+	if dropped := logging.Dropped(); dropped > 0 {
+		serverLog.Warn().Uint64("dropped", dropped).Msg("Log lines were dropped as the log buffer was full")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), logFlushTimeout)
+	defer cancel()
+
+	if err := logging.Flush(ctx); err != nil {
+		// The logger can't be used to report its own failure.
+		fmt.Fprintf(os.Stderr, "Failed to flush log buffer: %v\n", err)
 	}
 }
 
@@ -127,16 +393,34 @@ func resolvePort(config Config) int {
 	return defaultHTTPPort
 }
 
-// resolveAddr returns the listen address for the configured port.
+// resolveAddr returns the listen address from config. The address of a
+// pre-bound listener takes precedence over ListenAddr and Port.
 func resolveAddr(config Config) string {
-	return fmt.Sprintf(":%d", resolvePort(config))
+	switch {
+	case config.Listener != nil:
+		return config.Listener.Addr().String()
+	case len(config.ListenAddr) > 0:
+		return config.ListenAddr
+	default:
+		return fmt.Sprintf(":%d", resolvePort(config))
+	}
+}
+
+// resolveNetwork returns the listen network from config, or fallback when
+// none is configured.
+func resolveNetwork(config Config, fallback string) string {
+	if len(config.Network) > 0 {
+		return config.Network
+	}
+	return fallback
 }
 
 // buildTLSConfig creates a TLS config with rotating certificates when both
-// certificate paths are set. Returns nil when TLS is not configured.
-func buildTLSConfig(config Config) (*tls.Config, error) {
+// certificate paths are set. The certificate handler is returned for forced
+// reloads. Returns nil when TLS is not configured.
+func buildTLSConfig(config Config) (*tls.Config, *fileBasedCert, error) {
 	if len(config.PathTLSCert) == 0 || len(config.PathTLSKey) == 0 {
-		return nil, nil
+		return nil, nil, nil
 	}
 
 	reloadDuration := defaultCertCacheDuration
@@ -144,7 +428,7 @@ func buildTLSConfig(config Config) (*tls.Config, error) {
 		reloadDuration = config.CertCacheDuration
 	}
 
-	log.Debug().Msgf(
+	tlsLog.Debug().Msgf(
 		"Using TLS certificate %s and key %s",
 		config.PathTLSCert,
 		config.PathTLSKey,
@@ -152,7 +436,7 @@ func buildTLSConfig(config Config) (*tls.Config, error) {
 
 	cert := newFileBasedCert(config.PathTLSCert, config.PathTLSKey, reloadDuration)
 	if _, err := cert.GetCertificate(); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	return &tls.Config{
@@ -164,21 +448,10 @@ func buildTLSConfig(config Config) (*tls.Config, error) {
 			if err := hello.SupportsCertificate(loaded); err != nil {
 				// This error will be hidden by go's standard library, so we
 				// log it here.
-				log.Error().Err(err).Msg("Certificate does not match client requirements")
+				tlsLog.Error().Err(err).Msg("Certificate does not match client requirements")
 				return nil, err
 			}
 			return loaded, nil
 		},
-	}, nil
-}
-
-// probeStatus maps a Check result to an HTTP status code.
-func probeStatus(ctx context.Context, check Check) int {
-	if check == nil {
-		return http.StatusOK
-	}
-	if err := check(ctx); err != nil {
-		return http.StatusServiceUnavailable
-	}
-	return http.StatusOK
+	}, cert, nil
 }
@@ -1,18 +1,24 @@
 package httpserver
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
+	"encoding/json"
 	"errors"
 	"io"
 	"net"
 	"net/http"
+	"os"
+	"regexp"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/trivago/go-bootstrap/v2/logging"
 	"github.com/valyala/fasthttp"
 	"github.com/valyala/fasthttp/fasthttputil"
 )
@@ -392,6 +398,40 @@ func TestResolvePortDefaults(t *testing.T) {
 	}
 }
 
+// TestDrain verifies the drain phase waits for the drain delay, unless
+// another signal arrives, and runs during after marking the server.
+func TestDrain(t *testing.T) {
+	t.Parallel()
+
+	srv, err := NewWithConfig(Config{DrainDelay: 50 * time.Millisecond}, nil)
+	require.NoError(t, err)
+
+	started := time.Now()
+	drainingDuring := false
+	drain(srv, make(chan os.Signal), nil, func() {
+		drainingDuring = srv.state.draining.Load()
+	})
+	assert.GreaterOrEqual(t, time.Since(started), 50*time.Millisecond)
+	assert.True(t, drainingDuring)
+
+	srv, err = NewWithConfig(Config{DrainDelay: time.Hour}, nil)
+	require.NoError(t, err)
+
+	signals := make(chan os.Signal, 1)
+	signals <- syscall.SIGTERM
+	started = time.Now()
+	drain(srv, signals, nil, func() {})
+	assert.Less(t, time.Since(started), time.Second)
+
+	// Servers without a delay are not drained.
+	srv, err = NewWithConfig(Config{}, nil)
+	require.NoError(t, err)
+	ran := false
+	drain(srv, make(chan os.Signal), nil, func() { ran = true })
+	assert.False(t, srv.state.draining.Load())
+	assert.True(t, ran)
+}
+
 // startHTTPServer starts srv on a local listener and returns it.
 func startHTTPServer(t *testing.T, srv *HTTPServer) net.Listener {
 	t.Helper()
@@ -431,3 +471,187 @@ func startFastHTTPServer(t *testing.T, srv *FastHTTPServer) *fasthttp.Client {
 		},
 	}
 }
+
+// TestResolveAddr verifies the precedence of Listener, ListenAddr and Port.
+func TestResolveAddr(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() {
+		_ = listener.Close()
+	}()
+
+	tests := []struct {
+		// name describes the test case.
+		name string
+		// config is the server configuration.
+		config Config
+		// want is the expected listen address.
+		want string
+	}{
+		{name: "port", config: Config{Port: 9090}, want: ":9090"},
+		{name: "listen addr", config: Config{Port: 9090, ListenAddr: "127.0.0.1:8081"}, want: "127.0.0.1:8081"},
+		{name: "socket path", config: Config{Network: "unix", ListenAddr: "/run/app.sock"}, want: "/run/app.sock"},
+		{name: "listener", config: Config{ListenAddr: ":8081", Listener: listener}, want: listener.Addr().String()},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, resolveAddr(tt.config))
+		})
+	}
+}
+
+// TestCustomListeners verifies all server types serve on pre-bound
+// listeners and Unix domain sockets, replacing stale socket files.
+func TestCustomListeners(t *testing.T) {
+	t.Parallel()
+
+	servers := map[string]func(config Config) (Server, error){
+		"net/http": func(config Config) (Server, error) {
+			return NewWithConfig(config, nil)
+		},
+		"fasthttp": func(config Config) (Server, error) {
+			return NewFastHTTPWithConfig(config, nil)
+		},
+		"gin": func(config Config) (Server, error) {
+			return NewGinWithConfig(GinConfig{
+				ListenAddr: config.ListenAddr,
+				Network:    config.Network,
+				Listener:   config.Listener,
+			})
+		},
+	}
+
+	tests := []struct {
+		// name describes the test case.
+		name string
+		// unix serves on a Unix domain socket instead of a pre-bound TCP
+		// listener.
+		unix bool
+	}{
+		{name: "listener"},
+		{name: "unix socket", unix: true},
+	}
+
+	for serverName, newServer := range servers {
+		for _, tt := range tests {
+			serverName, newServer, tt := serverName, newServer, tt
+			t.Run(serverName+"/"+tt.name, func(t *testing.T) {
+				t.Parallel()
+
+				var (
+					config Config
+					dial   func(ctx context.Context, _, _ string) (net.Conn, error)
+				)
+				if tt.unix {
+					path := t.TempDir() + "/server.sock"
+
+					// Leave a stale socket file behind.
+					stale, err := net.Listen("unix", path)
+					require.NoError(t, err)
+					stale.(*net.UnixListener).SetUnlinkOnClose(false)
+					require.NoError(t, stale.Close())
+
+					config = Config{Network: "unix", ListenAddr: path}
+					dial = func(ctx context.Context, _, _ string) (net.Conn, error) {
+						return (&net.Dialer{}).DialContext(ctx, "unix", path)
+					}
+				} else {
+					listener, err := net.Listen("tcp", "127.0.0.1:0")
+					require.NoError(t, err)
+
+					config = Config{Listener: listener}
+					addr := listener.Addr().String()
+					dial = func(ctx context.Context, _, _ string) (net.Conn, error) {
+						return (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+					}
+				}
+
+				srv, err := newServer(config)
+				require.NoError(t, err)
+
+				done := make(chan error, 1)
+				go func() {
+					done <- srv.ListenAndServe()
+				}()
+
+				client := &http.Client{
+					Transport: &http.Transport{DialContext: dial},
+					Timeout:   time.Second,
+				}
+				require.Eventually(t, func() bool {
+					response, err := client.Get("http://server" + healthPath)
+					if err != nil {
+						return false
+					}
+					_ = response.Body.Close()
+					return response.StatusCode == http.StatusOK
+				}, 5*time.Second, 10*time.Millisecond)
+
+				require.NoError(t, srv.Shutdown(context.Background()))
+				assert.NoError(t, <-done)
+			})
+		}
+	}
+}
+
+// TestErrorLogRules verifies the levels of lines written to the ErrorLog of
+// net/http and Gin servers are detected through the default or configured
+// rules. The test modifies the global logger and must not run in parallel.
+func TestErrorLogRules(t *testing.T) {
+	output := &bytes.Buffer{}
+	require.NoError(t, logging.Configure(logging.Options{Output: output}))
+	previous := logging.GetLogLevel()
+	require.NoError(t, logging.SetLogLevel("trace"))
+	t.Cleanup(func() {
+		require.NoError(t, logging.SetLogLevel(previous.String()))
+		require.NoError(t, logging.Configure(logging.Options{}))
+	})
+
+	rules := []logging.LevelRule{
+		{Pattern: regexp.MustCompile(`^custom`), Level: zerolog.InfoLevel},
+	}
+
+	httpDefault, err := NewWithConfig(Config{}, nil)
+	require.NoError(t, err)
+	httpCustom, err := NewWithConfig(Config{ErrorLogRules: rules}, nil)
+	require.NoError(t, err)
+	ginDefault, err := NewGinWithConfig(GinConfig{})
+	require.NoError(t, err)
+	ginCustom, err := NewGinWithConfig(GinConfig{ErrorLogRules: rules})
+	require.NoError(t, err)
+
+	tests := []struct {
+		// name describes the test case.
+		name string
+		// srv is the server writing the line.
+		srv *HTTPServer
+		// line is written to the ErrorLog.
+		line string
+		// wantSeverity is the expected severity of the log event.
+		wantSeverity string
+	}{
+		{name: "net/http default rule", srv: httpDefault, line: "http: TLS handshake error from 10.0.0.1:4711: EOF", wantSeverity: "DEBUG"},
+		{name: "net/http fallback", srv: httpDefault, line: "custom line", wantSeverity: "ERROR"},
+		{name: "net/http custom rule", srv: httpCustom, line: "custom line", wantSeverity: "INFO"},
+		{name: "net/http custom rules replace defaults", srv: httpCustom, line: "http: TLS handshake error from 10.0.0.1:4711: EOF", wantSeverity: "ERROR"},
+		{name: "gin default rule", srv: ginDefault, line: "http: TLS handshake error from 10.0.0.1:4711: EOF", wantSeverity: "DEBUG"},
+		{name: "gin custom rule", srv: ginCustom, line: "custom line", wantSeverity: "INFO"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output.Reset()
+			tt.srv.Server.ErrorLog.Print(tt.line)
+
+			event := map[string]any{}
+			require.NoError(t, json.Unmarshal(output.Bytes(), &event))
+			assert.Equal(t, tt.wantSeverity, event["severity"])
+			assert.Equal(t, tt.line, event["message"])
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package httpserver
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// defaultHookTimeout bounds a shutdown hook without a timeout.
+const defaultHookTimeout = 10 * time.Second
+
+// ShutdownPhase defines when a ShutdownHook is run by Run.
+type ShutdownPhase int
+
+const (
+	// BeforeDrain hooks run after a stop signal was received, once the
+	// readiness probe fails. They run during the drain delay, before the
+	// server is shut down.
+	BeforeDrain ShutdownPhase = iota
+	// AfterStop hooks run after the server stopped accepting requests and
+	// all in-flight requests were completed or the shutdown timed out.
+	AfterStop
+	// CloseResources hooks run last, e.g. to close database pools or
+	// message producers used by the handlers.
+	CloseResources
+)
+
+// String returns the name of the phase.
+func (phase ShutdownPhase) String() string {
+	switch phase {
+	case BeforeDrain:
+		return "beforeDrain"
+	case AfterStop:
+		return "afterStop"
+	case CloseResources:
+		return "closeResources"
+	default:
+		return fmt.Sprintf("ShutdownPhase(%d)", int(phase))
+	}
+}
+
+// ShutdownHook is a function run by Run while the server shuts down.
+// Hooks of the same phase run in the order they were registered. Hooks are
+// run as well when the server fails to start.
+type ShutdownHook struct {
+	// Name identifies the hook in log messages.
+	Name string
+
+	// Phase defines when the hook is run.
+	Phase ShutdownPhase
+
+	// Timeout bounds the duration of the hook. Hooks exceeding it are
+	// abandoned and reported as failed.
+	// Defaults to 10 seconds when left empty.
+	Timeout time.Duration
+
+	// Run is the function to run. A returned error is logged and doesn't
+	// stop the remaining hooks.
+	Run func(ctx context.Context) error
+}
+
+// ListenOption configures Run and Listen.
+type ListenOption func(options *listenOptions)
+
+// listenOptions holds the configuration of Run.
+type listenOptions struct {
+	// signalHandler is called with the stop signal received.
+	signalHandler func(os.Signal)
+	// shutdownSignals are the signals stopping the server.
+	shutdownSignals []os.Signal
+	// signalActions maps signals to actions run without stopping.
+	signalActions map[os.Signal]SignalAction
+	// handoverSignal starts a new process taking over the listeners.
+	handoverSignal os.Signal
+	// shutdownTimeout bounds the graceful shutdown of the server.
+	shutdownTimeout time.Duration
+	// hooks are run while shutting down, in registration order.
+	hooks []ShutdownHook
+}
+
+// newListenOptions applies opts on top of the defaults.
+func newListenOptions(opts []ListenOption) listenOptions {
+	options := listenOptions{
+		shutdownTimeout: defaultShutdownTimeout,
+		shutdownSignals: defaultShutdownSignals,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// WithShutdownTimeout bounds the graceful shutdown of the server, not
+// including the drain delay and shutdown hooks. It also bounds shutting down
+// the other servers of a Group when one of them fails. Defaults to 30
+// seconds.
+func WithShutdownTimeout(timeout time.Duration) ListenOption {
+	return func(options *listenOptions) {
+		if timeout > 0 {
+			options.shutdownTimeout = timeout
+		}
+	}
+}
+
+// WithSignalHandler registers handler to be called with the received stop
+// signal before the server shuts down.
+func WithSignalHandler(handler func(os.Signal)) ListenOption {
+	return func(options *listenOptions) {
+		options.signalHandler = handler
+	}
+}
+
+// WithShutdownHook registers hooks to run while the server shuts down.
+func WithShutdownHook(hooks ...ShutdownHook) ListenOption {
+	return func(options *listenOptions) {
+		options.hooks = append(options.hooks, hooks...)
+	}
+}
+
+// runShutdownHooks runs all hooks of phase in registration order.
+func runShutdownHooks(hooks []ShutdownHook, phase ShutdownPhase) {
+	for _, hook := range hooks {
+		if hook.Phase != phase || hook.Run == nil {
+			continue
+		}
+
+		started := time.Now()
+		serverLog.Info().
+			Str("hook", hook.Name).
+			Stringer("phase", phase).
+			Msg("Running shutdown hook")
+
+		if err := runShutdownHook(hook); err != nil {
+			serverLog.Error().
+				Err(err).
+				Str("hook", hook.Name).
+				Stringer("phase", phase).
+				Dur("latency", time.Since(started)).
+				Msg("Shutdown hook failed")
+			continue
+		}
+
+		serverLog.Info().
+			Str("hook", hook.Name).
+			Stringer("phase", phase).
+			Dur("latency", time.Since(started)).
+			Msg("Shutdown hook completed")
+	}
+}
+
+// runShutdownHook runs hook bounded by its timeout. Hooks ignoring their
+// context are abandoned when the timeout expires. Panics are reported as
+// errors.
+func runShutdownHook(hook ShutdownHook) error {
+	timeout := hook.Timeout
+	if timeout <= 0 {
+		timeout = defaultHookTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				done <- fmt.Errorf("panic: %v", recovered)
+			}
+		}()
+		done <- hook.Run(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
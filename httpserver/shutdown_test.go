@@ -0,0 +1,246 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeServer is a Server recording its lifecycle.
+type fakeServer struct {
+	// serve is called by ListenAndServe.
+	serve func(srv *fakeServer) error
+	// stopped is closed by Shutdown.
+	stopped chan struct{}
+	// stopOnce guards closing stopped.
+	stopOnce sync.Once
+	// shutdownErr is returned by Shutdown.
+	shutdownErr error
+}
+
+// ListenAndServe calls serve.
+func (srv *fakeServer) ListenAndServe() error {
+	return srv.serve(srv)
+}
+
+// Shutdown unblocks ListenAndServe.
+func (srv *fakeServer) Shutdown(context.Context) error {
+	srv.stopOnce.Do(func() {
+		close(srv.stopped)
+	})
+	return srv.shutdownErr
+}
+
+// recordingHooks returns one hook per phase, registered in reverse order,
+// and the names of the hooks run.
+func recordingHooks() ([]ShutdownHook, func() []string) {
+	var (
+		guard sync.Mutex
+		ran   []string
+	)
+	record := func(name string) func(context.Context) error {
+		return func(context.Context) error {
+			guard.Lock()
+			defer guard.Unlock()
+			ran = append(ran, name)
+			return nil
+		}
+	}
+
+	hooks := []ShutdownHook{
+		{Name: "close", Phase: CloseResources, Run: record("close")},
+		{Name: "stopped", Phase: AfterStop, Run: record("stopped")},
+		{Name: "before", Phase: BeforeDrain, Run: record("before")},
+		{Name: "close2", Phase: CloseResources, Run: record("close2")},
+	}
+	return hooks, func() []string {
+		guard.Lock()
+		defer guard.Unlock()
+		return append([]string(nil), ran...)
+	}
+}
+
+// TestListenShutdownHooks verifies the hooks run by phase after a stop
+// signal and when the server fails to start.
+//
+// The test sends SIGTERM to its own process, so it must not run in parallel.
+func TestListenShutdownHooks(t *testing.T) {
+	want := []string{"before", "stopped", "close", "close2"}
+
+	hooks, ran := recordingHooks()
+	srv := &fakeServer{
+		stopped: make(chan struct{}),
+		serve: func(srv *fakeServer) error {
+			// Listen registered its signal handler before starting the server.
+			_ = syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+			<-srv.stopped
+			return nil
+		},
+	}
+	Listen(srv, nil, WithShutdownHook(hooks...), WithShutdownTimeout(time.Second))
+	assert.Equal(t, want, ran())
+
+	hooks, ran = recordingHooks()
+	srv = &fakeServer{
+		stopped: make(chan struct{}),
+		serve: func(*fakeServer) error {
+			return errors.New("address already in use")
+		},
+	}
+	Listen(srv, nil, WithShutdownHook(hooks...))
+	assert.Equal(t, want, ran())
+}
+
+// TestRunShutdownHook verifies timeouts, errors and panics of hooks.
+func TestRunShutdownHook(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// name identifies the test case.
+		name string
+		// run is the hook function.
+		run func(ctx context.Context) error
+		// wantErr is the expected error message, empty for success.
+		wantErr string
+	}{
+		{
+			name: "success",
+			run:  func(context.Context) error { return nil },
+		},
+		{
+			name:    "error",
+			run:     func(context.Context) error { return errors.New("flush failed") },
+			wantErr: "flush failed",
+		},
+		{
+			name: "timeout",
+			run: func(ctx context.Context) error {
+				<-ctx.Done()
+				time.Sleep(time.Second)
+				return nil
+			},
+			wantErr: context.DeadlineExceeded.Error(),
+		},
+		{
+			name:    "panic",
+			run:     func(context.Context) error { panic("boom") },
+			wantErr: "panic: boom",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := runShutdownHook(ShutdownHook{
+				Name:    tt.name,
+				Timeout: 20 * time.Millisecond,
+				Run:     tt.run,
+			})
+			if len(tt.wantErr) == 0 {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestListenOptions verifies the defaults and options of Listen.
+func TestListenOptions(t *testing.T) {
+	t.Parallel()
+
+	options := newListenOptions(nil)
+	assert.Equal(t, defaultShutdownTimeout, options.shutdownTimeout)
+	assert.Empty(t, options.hooks)
+
+	options = newListenOptions([]ListenOption{
+		WithShutdownTimeout(5 * time.Second),
+		WithShutdownHook(ShutdownHook{Name: "a"}),
+		WithShutdownHook(ShutdownHook{Name: "b"}),
+	})
+	assert.Equal(t, 5*time.Second, options.shutdownTimeout)
+	assert.Len(t, options.hooks, 2)
+	assert.Equal(t, "afterStop", AfterStop.String())
+}
+
+// TestRun verifies Run stops on context cancellation and reports startup
+// and shutdown errors.
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	blocking := func(srv *fakeServer) error {
+		<-srv.stopped
+		return nil
+	}
+
+	tests := []struct {
+		// name identifies the test case.
+		name string
+		// srv is the server to run.
+		srv *fakeServer
+		// wantErr lists the sentinel errors expected to be wrapped.
+		wantErr []error
+		// wantCause is expected to be wrapped as well, if set.
+		wantCause error
+	}{
+		{
+			name: "canceled",
+			srv:  &fakeServer{serve: blocking},
+		},
+		{
+			name: "startup failure",
+			srv: &fakeServer{serve: func(*fakeServer) error {
+				return syscall.EADDRINUSE
+			}},
+			wantErr:   []error{ErrStartup},
+			wantCause: syscall.EADDRINUSE,
+		},
+		{
+			name:      "shutdown failure",
+			srv:       &fakeServer{serve: blocking, shutdownErr: context.DeadlineExceeded},
+			wantErr:   []error{ErrShutdown},
+			wantCause: context.DeadlineExceeded,
+		},
+		{
+			name: "both failed",
+			srv: &fakeServer{
+				serve: func(srv *fakeServer) error {
+					<-srv.stopped
+					return errors.New("connection reset")
+				},
+				shutdownErr: context.DeadlineExceeded,
+			},
+			wantErr: []error{ErrStartup, ErrShutdown},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tt.srv.stopped = make(chan struct{})
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+
+			err := Run(ctx, tt.srv, WithShutdownTimeout(time.Second))
+			if len(tt.wantErr) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			for _, want := range tt.wantErr {
+				assert.ErrorIs(t, err, want)
+			}
+			if tt.wantCause != nil {
+				assert.ErrorIs(t, err, tt.wantCause)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package httpserver
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/signal"
+	"runtime"
+	"runtime/pprof"
+	"syscall"
+
+	"github.com/trivago/go-bootstrap/v2/config"
+)
+
+// defaultShutdownSignals are the signals stopping the server unless
+// configured otherwise through WithShutdownSignals.
+var defaultShutdownSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT}
+
+// SignalAction is run by Run when a signal registered through
+// WithSignalAction is received. The server keeps running. A returned error
+// is logged.
+type SignalAction func(ctx context.Context, srv Server) error
+
+// Reloader is implemented by servers supporting a reload without restart,
+// e.g. of their TLS certificates.
+type Reloader interface {
+	// Reload reloads the configuration of the server.
+	Reload(ctx context.Context) error
+}
+
+// Reload is a SignalAction re-reading the configuration through
+// config.Reload and reloading the server, if it implements Reloader. For
+// servers of this package, the TLS certificate is reloaded from disk.
+// Log files are not reopened, as the logging package does so on SIGHUP.
+func Reload(ctx context.Context, srv Server) error {
+	config.Reload()
+	syncLogThresholds()
+
+	if reloader, ok := srv.(Reloader); ok {
+		return reloader.Reload(ctx)
+	}
+	return nil
+}
+
+// DumpGoroutines is a SignalAction logging the stack traces of all
+// goroutines.
+func DumpGoroutines(context.Context, Server) error {
+	var dump bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&dump, 2); err != nil {
+		return err
+	}
+
+	serverLog.Info().
+		Int("goroutines", runtime.NumGoroutine()).
+		Msgf("Goroutine dump\n\n%s", dump.String())
+	return nil
+}
+
+// WithShutdownSignals replaces the signals stopping the server, which are
+// SIGINT, SIGTERM and SIGQUIT by default.
+func WithShutdownSignals(signals ...os.Signal) ListenOption {
+	return func(options *listenOptions) {
+		options.shutdownSignals = signals
+	}
+}
+
+// WithSignalAction runs action whenever sig is received, instead of stopping
+// the server. Actions take precedence over shutdown signals.
+func WithSignalAction(sig os.Signal, action SignalAction) ListenOption {
+	return func(options *listenOptions) {
+		if options.signalActions == nil {
+			options.signalActions = map[os.Signal]SignalAction{}
+		}
+		options.signalActions[sig] = action
+	}
+}
+
+// WithHandover starts a new process of the current executable whenever sig
+// is received, passing all listeners to it. Once the new process accepts
+// connections on all listeners, this server is drained and stopped, so no
+// connection is refused during a binary upgrade. The server keeps running
+// when the new process can't be started, exits or doesn't accept connections
+// within 30 seconds, in which case it is killed. Handovers take precedence
+// over actions and shutdown signals.
+func WithHandover(sig os.Signal) ListenOption {
+	return func(options *listenOptions) {
+		options.handoverSignal = sig
+	}
+}
+
+// signalChannels receive the signals handled by Run.
+type signalChannels struct {
+	// shutdown receives the signals stopping the server.
+	shutdown chan os.Signal
+	// actions receives the signals with an action.
+	actions chan os.Signal
+	// handover receives the handover signal.
+	handover chan os.Signal
+}
+
+// notifySignals subscribes to the shutdown signals, the signals with an
+// action and the handover signal.
+func notifySignals(options listenOptions) signalChannels {
+	channels := signalChannels{
+		shutdown: make(chan os.Signal, 1),
+		actions:  make(chan os.Signal, 1),
+		handover: make(chan os.Signal, 1),
+	}
+
+	shutdownSignals, actionSignals := splitSignals(options)
+	if len(shutdownSignals) > 0 {
+		signal.Notify(channels.shutdown, shutdownSignals...)
+	}
+	if len(actionSignals) > 0 {
+		signal.Notify(channels.actions, actionSignals...)
+	}
+	if options.handoverSignal != nil {
+		signal.Notify(channels.handover, options.handoverSignal)
+	}
+	return channels
+}
+
+// stop unsubscribes from all signals.
+func (channels signalChannels) stop() {
+	signal.Stop(channels.shutdown)
+	signal.Stop(channels.actions)
+	signal.Stop(channels.handover)
+}
+
+// splitSignals returns the signals stopping the server and the signals with
+// an action. Signals with an action are never shutdown signals, and the
+// handover signal is neither.
+func splitSignals(options listenOptions) (shutdownSignals, actionSignals []os.Signal) {
+	for _, sig := range options.shutdownSignals {
+		if _, isAction := options.signalActions[sig]; !isAction && sig != options.handoverSignal {
+			shutdownSignals = append(shutdownSignals, sig)
+		}
+	}
+	for sig := range options.signalActions {
+		if sig != options.handoverSignal {
+			actionSignals = append(actionSignals, sig)
+		}
+	}
+	return shutdownSignals, actionSignals
+}
+
+// runSignalAction runs the action registered for sig, logging its result.
+func runSignalAction(ctx context.Context, srv Server, options listenOptions, sig os.Signal) {
+	action := options.signalActions[sig]
+	if action == nil {
+		return
+	}
+
+	serverLog.Info().Msgf("Received signal: %s, running action", sig.String())
+	if err := action(ctx, srv); err != nil {
+		serverLog.Error().Err(err).Msgf("Action for signal %s failed", sig.String())
+	}
+}
+
+// Reload reloads the TLS certificate from disk, if TLS is enabled.
+func (s *HTTPServer) Reload(context.Context) error {
+	return s.cert.Reload()
+}
+
+// Reload reloads the TLS certificate from disk, if TLS is enabled.
+func (s *FastHTTPServer) Reload(context.Context) error {
+	return s.cert.Reload()
+}
@@ -0,0 +1,122 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRunSignalActions verifies action signals keep the server running,
+// while the configured shutdown signals stop it.
+//
+// The test sends signals to its own process, so it must not run in parallel.
+func TestRunSignalActions(t *testing.T) {
+	var actions atomic.Int32
+	action := func(_ context.Context, srv Server) error {
+		actions.Add(1)
+		// The server keeps running while the action is run.
+		_ = syscall.Kill(syscall.Getpid(), syscall.SIGUSR2)
+		return errors.New("action failed")
+	}
+
+	srv := &fakeServer{
+		stopped: make(chan struct{}),
+		serve: func(srv *fakeServer) error {
+			// Run subscribed to all signals before starting the server.
+			_ = syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+			<-srv.stopped
+			return nil
+		},
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- Run(context.Background(), srv,
+			WithShutdownSignals(syscall.SIGUSR2),
+			WithSignalAction(syscall.SIGUSR1, action),
+		)
+	}()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return")
+	}
+	assert.Equal(t, int32(1), actions.Load())
+}
+
+// TestSplitSignals verifies actions take precedence over shutdown signals.
+func TestSplitSignals(t *testing.T) {
+	t.Parallel()
+
+	shutdownSignals, actionSignals := splitSignals(newListenOptions(nil))
+	assert.Equal(t, defaultShutdownSignals, shutdownSignals)
+	assert.Empty(t, actionSignals)
+
+	shutdownSignals, actionSignals = splitSignals(newListenOptions([]ListenOption{
+		WithSignalAction(syscall.SIGTERM, DumpGoroutines),
+		WithSignalAction(syscall.SIGHUP, Reload),
+	}))
+	assert.Equal(t, []os.Signal{syscall.SIGINT, syscall.SIGQUIT}, shutdownSignals)
+	assert.ElementsMatch(t, []os.Signal{syscall.SIGTERM, syscall.SIGHUP}, actionSignals)
+
+	shutdownSignals, actionSignals = splitSignals(newListenOptions([]ListenOption{
+		WithSignalAction(syscall.SIGHUP, Reload),
+		WithSignalAction(syscall.SIGUSR2, Reload),
+		WithHandover(syscall.SIGUSR2),
+		WithShutdownSignals(syscall.SIGTERM, syscall.SIGUSR2),
+	}))
+	assert.Equal(t, []os.Signal{syscall.SIGTERM}, shutdownSignals)
+	assert.Equal(t, []os.Signal{syscall.SIGHUP}, actionSignals)
+}
+
+// TestReload verifies TLS certificates are reloaded from disk.
+func TestReload(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.cert")
+	keyFile := filepath.Join(dir, "tls.key")
+	copyFile(t, "../hack/tls.cert", certFile)
+	copyFile(t, "../hack/tls.key", keyFile)
+
+	srv, err := NewWithConfig(Config{PathTLSCert: certFile, PathTLSKey: keyFile}, nil)
+	require.NoError(t, err)
+	fastSrv, err := NewFastHTTPWithConfig(Config{}, nil)
+	require.NoError(t, err)
+
+	cached := srv.cert.cert
+	require.NoError(t, Reload(context.Background(), Group{srv, fastSrv}))
+	assert.NotSame(t, cached, srv.cert.cert)
+
+	// A broken certificate keeps the cached one.
+	cached = srv.cert.cert
+	require.NoError(t, os.WriteFile(certFile, []byte("broken"), 0o600))
+	assert.Error(t, srv.Reload(context.Background()))
+	assert.Same(t, cached, srv.cert.cert)
+}
+
+// TestDumpGoroutines verifies the goroutine dump action.
+func TestDumpGoroutines(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, DumpGoroutines(context.Background(), nil))
+}
+
+// copyFile copies the file at from to to.
+func copyFile(t *testing.T, from, to string) {
+	t.Helper()
+
+	data, err := os.ReadFile(from)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(to, data, 0o600))
+}
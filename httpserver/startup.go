@@ -0,0 +1,204 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// startedCheckName is the name of the check failing until MarkStarted is
+	// called.
+	startedCheckName = "started"
+	// readyCheckName is the name of the check failing until MarkReady is
+	// called.
+	readyCheckName = "ready"
+)
+
+var (
+	// errNotStarted is reported by the started check before MarkStarted.
+	errNotStarted = errors.New("startup not complete")
+	// errNotReady is reported by the ready check before MarkReady.
+	errNotReady = errors.New("not marked ready")
+	// errDraining is reported by the ready check after MarkDraining.
+	errDraining = errors.New("draining")
+)
+
+// lifecycle tracks whether a server completed its startup, is ready to
+// receive traffic or is draining before shutdown. It is shared by the main
+// and the management server.
+type lifecycle struct {
+	// started is set once the startup completed.
+	started atomic.Bool
+	// ready is set once the server may receive traffic.
+	ready atomic.Bool
+	// draining is set once the server is about to shut down.
+	draining atomic.Bool
+	// deferStartup reports whether the started check is enforced.
+	deferStartup bool
+	// deferReady reports whether the ready check is enforced.
+	deferReady bool
+	// drainDelay is the duration to keep serving while draining.
+	drainDelay time.Duration
+}
+
+// newLifecycle creates the lifecycle of a server. Without DeferStartup and
+// DeferReady, the server is started and ready from the beginning.
+func newLifecycle(config Config) *lifecycle {
+	state := &lifecycle{
+		deferStartup: config.DeferStartup,
+		deferReady:   config.DeferReady,
+		drainDelay:   config.DrainDelay,
+	}
+	state.started.Store(!config.DeferStartup)
+	state.ready.Store(!config.DeferReady)
+	return state
+}
+
+// markStarted marks the startup as complete.
+func (state *lifecycle) markStarted() {
+	if state == nil {
+		return
+	}
+	if !state.started.Swap(true) && state.deferStartup {
+		serverLog.Info().Msg("Startup complete")
+	}
+}
+
+// markReady marks the startup as complete and the server as ready.
+func (state *lifecycle) markReady() {
+	if state == nil {
+		return
+	}
+	state.markStarted()
+	if !state.ready.Swap(true) && state.deferReady {
+		serverLog.Info().Msg("Server is ready")
+	}
+}
+
+// markDraining lets the ready check fail from now on.
+func (state *lifecycle) markDraining() {
+	if state == nil {
+		return
+	}
+	state.draining.Store(true)
+}
+
+// delay returns the drain delay, or zero for servers created without a
+// constructor.
+func (state *lifecycle) delay() time.Duration {
+	if state == nil {
+		return 0
+	}
+	return state.drainDelay
+}
+
+// checkStarted is a Check failing until the startup is complete.
+func (state *lifecycle) checkStarted(context.Context) error {
+	if !state.started.Load() {
+		return errNotStarted
+	}
+	return nil
+}
+
+// checkReady is a Check failing until the startup is complete and the
+// server is ready, and again once the server is draining.
+func (state *lifecycle) checkReady(ctx context.Context) error {
+	if err := state.checkStarted(ctx); err != nil {
+		return err
+	}
+	if !state.ready.Load() {
+		return errNotReady
+	}
+	if state.draining.Load() {
+		return errDraining
+	}
+	return nil
+}
+
+// gatesReady reports whether the ready check can fail.
+func (state *lifecycle) gatesReady() bool {
+	return state != nil && (state.deferStartup || state.deferReady || state.drainDelay > 0)
+}
+
+// startupChecks returns checks extended by the started check when the
+// startup is deferred.
+func (state *lifecycle) startupChecks(checks Checks) Checks {
+	if state == nil || !state.deferStartup {
+		return checks
+	}
+	return withCheck(checks, startedCheckName, state.checkStarted)
+}
+
+// readyChecks returns checks extended by the ready check when the startup or
+// readiness is deferred or a drain delay is configured.
+func (state *lifecycle) readyChecks(checks Checks) Checks {
+	if !state.gatesReady() {
+		return checks
+	}
+	return withCheck(checks, readyCheckName, state.checkReady)
+}
+
+// gateGin runs handler only when check succeeds. Otherwise a failed probe
+// response naming the check is written.
+func gateGin(probe, name string, check Check, handler gin.HandlerFunc) gin.HandlerFunc {
+	gate := probeHTTP(probe, Checks{name: check}, 0)
+	return func(ctx *gin.Context) {
+		if check(ctx.Request.Context()) != nil {
+			gate(ctx.Writer, ctx.Request)
+			return
+		}
+		handler(ctx)
+	}
+}
+
+// MarkStarted marks the startup as complete, letting /startupz succeed.
+// Only required when DeferStartup is set.
+func (s *HTTPServer) MarkStarted() {
+	s.state.markStarted()
+}
+
+// MarkReady marks the startup as complete and the server as ready to
+// receive traffic, letting /startupz and /readyz succeed. Only required when
+// DeferStartup or DeferReady is set.
+func (s *HTTPServer) MarkReady() {
+	s.state.markReady()
+}
+
+// MarkDraining lets /readyz fail while the server keeps serving requests.
+// Called by Listen before the drain delay.
+func (s *HTTPServer) MarkDraining() {
+	s.state.markDraining()
+}
+
+// DrainDelay returns the configured drain delay.
+func (s *HTTPServer) DrainDelay() time.Duration {
+	return s.state.delay()
+}
+
+// MarkStarted marks the startup as complete, letting /startupz succeed.
+// Only required when DeferStartup is set.
+func (s *FastHTTPServer) MarkStarted() {
+	s.state.markStarted()
+}
+
+// MarkReady marks the startup as complete and the server as ready to
+// receive traffic, letting /startupz and /readyz succeed. Only required when
+// DeferStartup or DeferReady is set.
+func (s *FastHTTPServer) MarkReady() {
+	s.state.markReady()
+}
+
+// MarkDraining lets /readyz fail while the server keeps serving requests.
+// Called by Listen before the drain delay.
+func (s *FastHTTPServer) MarkDraining() {
+	s.state.markDraining()
+}
+
+// DrainDelay returns the configured drain delay.
+func (s *FastHTTPServer) DrainDelay() time.Duration {
+	return s.state.delay()
+}
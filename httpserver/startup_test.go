@@ -0,0 +1,202 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+// TestStartupGating verifies /startupz and /readyz follow MarkStarted and
+// MarkReady on all server types.
+func TestStartupGating(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// name identifies the test case.
+		name string
+		// deferStartup enables DeferStartup.
+		deferStartup bool
+		// deferReady enables DeferReady.
+		deferReady bool
+		// markStarted calls MarkStarted before the probes are requested.
+		markStarted bool
+		// markReady calls MarkReady before the probes are requested.
+		markReady bool
+		// wantStartup is the expected status of /startupz.
+		wantStartup int
+		// wantReady is the expected status of /readyz.
+		wantReady int
+	}{
+		{
+			name:        "not deferred",
+			wantStartup: http.StatusOK,
+			wantReady:   http.StatusOK,
+		},
+		{
+			name:         "startup pending",
+			deferStartup: true,
+			wantStartup:  http.StatusServiceUnavailable,
+			wantReady:    http.StatusServiceUnavailable,
+		},
+		{
+			name:         "started",
+			deferStartup: true,
+			markStarted:  true,
+			wantStartup:  http.StatusOK,
+			wantReady:    http.StatusOK,
+		},
+		{
+			name:         "started but not ready",
+			deferStartup: true,
+			deferReady:   true,
+			markStarted:  true,
+			wantStartup:  http.StatusOK,
+			wantReady:    http.StatusServiceUnavailable,
+		},
+		{
+			name:        "ready pending",
+			deferReady:  true,
+			wantStartup: http.StatusOK,
+			wantReady:   http.StatusServiceUnavailable,
+		},
+		{
+			name:         "ready",
+			deferStartup: true,
+			deferReady:   true,
+			markReady:    true,
+			wantStartup:  http.StatusOK,
+			wantReady:    http.StatusOK,
+		},
+	}
+
+	serve := func(handler http.Handler, path string) int {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder.Code
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			config := Config{DeferStartup: tt.deferStartup, DeferReady: tt.deferReady}
+			srv, err := NewWithConfig(config, nil)
+			require.NoError(t, err)
+			fastSrv, err := NewFastHTTPWithConfig(config, nil)
+			require.NoError(t, err)
+			ginSrv, err := NewGinWithConfig(GinConfig{DeferStartup: tt.deferStartup, DeferReady: tt.deferReady})
+			require.NoError(t, err)
+			customGinSrv, err := NewGinWithConfig(GinConfig{
+				DeferStartup: tt.deferStartup,
+				DeferReady:   tt.deferReady,
+				Ready:        AlwaysOk,
+				Startup:      AlwaysOk,
+			})
+			require.NoError(t, err)
+
+			for _, marker := range []interface {
+				MarkStarted()
+				MarkReady()
+			}{srv, fastSrv, ginSrv, customGinSrv} {
+				if tt.markStarted {
+					marker.MarkStarted()
+				}
+				if tt.markReady {
+					marker.MarkReady()
+				}
+			}
+
+			for path, want := range map[string]int{startupPath: tt.wantStartup, readyPath: tt.wantReady} {
+				assert.Equal(t, want, serve(srv.Server.Handler, path), "net/http %s", path)
+				assert.Equal(t, want, serve(ginSrv.Server.Handler, path), "gin %s", path)
+				assert.Equal(t, want, serve(customGinSrv.Server.Handler, path), "custom gin %s", path)
+
+				ctx := &fasthttp.RequestCtx{}
+				ctx.Request.SetRequestURI(path)
+				fastSrv.Server.Handler(ctx)
+				assert.Equal(t, want, ctx.Response.StatusCode(), "fasthttp %s", path)
+			}
+		})
+	}
+}
+
+// TestStartupChecks verifies the startup checks and the management port.
+func TestStartupChecks(t *testing.T) {
+	t.Parallel()
+
+	srv, err := NewWithConfig(Config{
+		ManagementPort: 9090,
+		Startup:        failingCheck,
+		DeferReady:     true,
+	}, nil)
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	srv.Management.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/startupz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "connection refused")
+
+	srv.MarkReady()
+	recorder = httptest.NewRecorder()
+	srv.Management.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	ginSrv, err := NewGinWithConfig(GinConfig{StartupChecks: Checks{"db": failingCheck}})
+	require.NoError(t, err)
+	recorder = httptest.NewRecorder()
+	ginSrv.Server.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/startupz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+
+	// Servers created without a constructor can still be marked.
+	assert.NotPanics(t, func() {
+		(&HTTPServer{}).MarkReady()
+		(&FastHTTPServer{}).MarkStarted()
+	})
+}
+
+// TestDrainingReadiness verifies /readyz fails once a server is draining.
+func TestDrainingReadiness(t *testing.T) {
+	t.Parallel()
+
+	serve := func(handler http.Handler) int {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, readyPath, nil))
+		return recorder.Code
+	}
+	serveFast := func(handler fasthttp.RequestHandler) int {
+		ctx := &fasthttp.RequestCtx{}
+		ctx.Request.SetRequestURI(readyPath)
+		handler(ctx)
+		return ctx.Response.StatusCode()
+	}
+
+	config := Config{DrainDelay: time.Second}
+	srv, err := NewWithConfig(config, nil)
+	require.NoError(t, err)
+	fastSrv, err := NewFastHTTPWithConfig(config, nil)
+	require.NoError(t, err)
+	ginSrv, err := NewGinWithConfig(GinConfig{DrainDelay: time.Second, Ready: AlwaysOk})
+	require.NoError(t, err)
+
+	assert.Equal(t, time.Second, srv.DrainDelay())
+	assert.Equal(t, time.Second, fastSrv.DrainDelay())
+	assert.Equal(t, time.Second, ginSrv.DrainDelay())
+
+	assert.Equal(t, http.StatusOK, serve(srv.Server.Handler))
+	assert.Equal(t, http.StatusOK, serveFast(fastSrv.Server.Handler))
+	assert.Equal(t, http.StatusOK, serve(ginSrv.Server.Handler))
+
+	srv.MarkDraining()
+	fastSrv.MarkDraining()
+	ginSrv.MarkDraining()
+
+	assert.Equal(t, http.StatusServiceUnavailable, serve(srv.Server.Handler))
+	assert.Equal(t, http.StatusServiceUnavailable, serveFast(fastSrv.Server.Handler))
+	assert.Equal(t, http.StatusServiceUnavailable, serve(ginSrv.Server.Handler))
+}
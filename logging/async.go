@@ -0,0 +1,226 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy defines how an AsyncWriter behaves when its buffer is full.
+type OverflowPolicy int
+
+const (
+	// OverflowDrop discards new lines while the buffer is full. Dropped
+	// lines are counted and reported by AsyncWriter.Dropped.
+	OverflowDrop OverflowPolicy = iota
+	// OverflowBlock blocks the log call until the buffer has space again.
+	OverflowBlock
+)
+
+// defaultAsyncBufferSize is the number of lines buffered by an AsyncWriter
+// when no size is given.
+const defaultAsyncBufferSize = 1024
+
+// AsyncWriter decouples log calls from a potentially slow output. Lines are
+// stored in a bounded ring buffer and written by a background goroutine.
+type AsyncWriter struct {
+	// out receives all buffered lines.
+	out io.Writer
+	// policy defines the behavior when the buffer is full.
+	policy OverflowPolicy
+
+	// guard protects the ring buffer and all state flags.
+	guard sync.Mutex
+	// changed is signaled whenever lines are added or removed, or the
+	// writer is closed.
+	changed *sync.Cond
+	// lines is the ring buffer of pending lines.
+	lines [][]byte
+	// head is the index of the oldest pending line.
+	head int
+	// count is the number of pending lines.
+	count int
+	// writing reports whether a line is currently written to out.
+	writing bool
+	// closed reports whether Close has been called.
+	closed bool
+
+	// dropped counts the lines discarded by OverflowDrop.
+	dropped atomic.Uint64
+	// done is closed when the background goroutine exits.
+	done chan struct{}
+}
+
+// NewAsyncWriter creates an AsyncWriter writing to out, buffering up to size
+// lines. A size of zero or less uses a default of 1024 lines.
+func NewAsyncWriter(out io.Writer, size int, policy OverflowPolicy) *AsyncWriter {
+	if size <= 0 {
+		size = defaultAsyncBufferSize
+	}
+
+	writer := &AsyncWriter{
+		out:    out,
+		policy: policy,
+		lines:  make([][]byte, size),
+		done:   make(chan struct{}),
+	}
+	writer.changed = sync.NewCond(&writer.guard)
+
+	go writer.run()
+	return writer
+}
+
+// Write copies p into the buffer. Depending on the overflow policy, Write
+// blocks or discards p when the buffer is full. After Close, p is written to
+// the output directly.
+func (w *AsyncWriter) Write(p []byte) (n int, err error) {
+	w.guard.Lock()
+
+	for !w.closed && w.count == len(w.lines) {
+		if w.policy == OverflowDrop {
+			w.guard.Unlock()
+			w.dropped.Add(1)
+			return len(p), nil
+		}
+		w.changed.Wait()
+	}
+
+	if w.closed {
+		w.guard.Unlock()
+		return w.out.Write(p)
+	}
+
+	// The caller may reuse p after Write returns.
+	w.lines[(w.head+w.count)%len(w.lines)] = append([]byte(nil), p...)
+	w.count++
+	w.changed.Broadcast()
+	w.guard.Unlock()
+
+	return len(p), nil
+}
+
+// Dropped returns the number of lines discarded because the buffer was full.
+func (w *AsyncWriter) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+// Flush blocks until all buffered lines have been written or ctx is done.
+func (w *AsyncWriter) Flush(ctx context.Context) error {
+	flushed := make(chan struct{})
+	go func() {
+		w.guard.Lock()
+		for w.count > 0 || w.writing {
+			w.changed.Wait()
+		}
+		w.guard.Unlock()
+		close(flushed)
+	}()
+
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close writes all buffered lines and stops the background goroutine.
+// Lines written after Close are written to the output directly.
+func (w *AsyncWriter) Close() error {
+	w.guard.Lock()
+	w.closed = true
+	w.changed.Broadcast()
+	w.guard.Unlock()
+
+	<-w.done
+	return nil
+}
+
+// run writes buffered lines until the writer is closed and the buffer is
+// empty.
+func (w *AsyncWriter) run() {
+	defer close(w.done)
+
+	w.guard.Lock()
+	defer w.guard.Unlock()
+
+	for {
+		for w.count == 0 && !w.closed {
+			w.changed.Wait()
+		}
+		if w.count == 0 {
+			return
+		}
+
+		line := w.lines[w.head]
+		w.lines[w.head] = nil
+		w.head = (w.head + 1) % len(w.lines)
+		w.count--
+		w.writing = true
+		w.guard.Unlock()
+
+		// Errors can't be reported, as this is the log output itself.
+		_, _ = w.out.Write(line)
+
+		w.guard.Lock()
+		w.writing = false
+		w.changed.Broadcast()
+	}
+}
+
+var (
+	// asyncGuard protects activeAsync and droppedBefore.
+	asyncGuard sync.Mutex
+	// activeAsync is the AsyncWriter used by the global logger, if any.
+	activeAsync *AsyncWriter
+	// droppedBefore counts the lines dropped by replaced AsyncWriters.
+	droppedBefore uint64
+)
+
+// Flush writes pending deduplication summaries and blocks until all lines
+// buffered for the global logger have been written or ctx is done.
+func Flush(ctx context.Context) error {
+	flushDedup()
+
+	asyncGuard.Lock()
+	async := activeAsync
+	asyncGuard.Unlock()
+
+	if async == nil {
+		return nil
+	}
+	return async.Flush(ctx)
+}
+
+// Dropped returns the number of lines the global logger discarded because
+// its async buffer was full.
+func Dropped() uint64 {
+	asyncGuard.Lock()
+	defer asyncGuard.Unlock()
+
+	if activeAsync == nil {
+		return droppedBefore
+	}
+	return droppedBefore + activeAsync.Dropped()
+}
+
+// setAsyncWriter replaces the AsyncWriter of the global logger. The previous
+// writer is closed after writing all of its buffered lines.
+func setAsyncWriter(async *AsyncWriter) {
+	asyncGuard.Lock()
+	previous := activeAsync
+	activeAsync = async
+	asyncGuard.Unlock()
+
+	if previous == nil {
+		return
+	}
+
+	// Close before reading the counter, so no more lines can be dropped.
+	_ = previous.Close()
+
+	asyncGuard.Lock()
+	droppedBefore += previous.Dropped()
+	asyncGuard.Unlock()
+}
@@ -0,0 +1,161 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingWriter blocks all writes until release is closed.
+type blockingWriter struct {
+	// release unblocks all pending and future writes when closed.
+	release chan struct{}
+	// guard protects lines.
+	guard sync.Mutex
+	// lines holds all written lines.
+	lines []string
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+
+	w.guard.Lock()
+	defer w.guard.Unlock()
+	w.lines = append(w.lines, string(p))
+	return len(p), nil
+}
+
+func (w *blockingWriter) written() []string {
+	w.guard.Lock()
+	defer w.guard.Unlock()
+	return append([]string(nil), w.lines...)
+}
+
+// TestAsyncWriterOrder verifies lines are written in order and the buffer
+// contents are copied.
+func TestAsyncWriterOrder(t *testing.T) {
+	t.Parallel()
+
+	out := &blockingWriter{release: make(chan struct{})}
+	close(out.release)
+
+	writer := NewAsyncWriter(out, 4, OverflowBlock)
+	line := []byte("first\n")
+	_, err := writer.Write(line)
+	require.NoError(t, err)
+
+	copy(line, "reused")
+	_, err = writer.Write([]byte("second\n"))
+	require.NoError(t, err)
+
+	require.NoError(t, writer.Flush(context.Background()))
+	assert.Equal(t, []string{"first\n", "second\n"}, out.written())
+	assert.Zero(t, writer.Dropped())
+	require.NoError(t, writer.Close())
+}
+
+// TestAsyncWriterDrop verifies lines are dropped and counted when the buffer
+// of a writer using OverflowDrop is full.
+func TestAsyncWriterDrop(t *testing.T) {
+	t.Parallel()
+
+	out := &blockingWriter{release: make(chan struct{})}
+	writer := NewAsyncWriter(out, 2, OverflowDrop)
+
+	// The first line is taken by the background goroutine and blocks, so
+	// it may or may not occupy the buffer when the next lines are written.
+	for i := 0; i < 10; i++ {
+		_, err := writer.Write([]byte("line\n"))
+		require.NoError(t, err)
+	}
+
+	close(out.release)
+	require.NoError(t, writer.Close())
+
+	written := len(out.written())
+	assert.GreaterOrEqual(t, written, 2)
+	assert.LessOrEqual(t, written, 3)
+	assert.Equal(t, uint64(10-written), writer.Dropped())
+}
+
+// TestAsyncWriterBlock verifies writes of a writer using OverflowBlock wait
+// for space in the buffer instead of dropping lines.
+func TestAsyncWriterBlock(t *testing.T) {
+	t.Parallel()
+
+	out := &blockingWriter{release: make(chan struct{})}
+	writer := NewAsyncWriter(out, 1, OverflowBlock)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 5; i++ {
+			_, _ = writer.Write([]byte("line\n"))
+		}
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("writes did not block on a full buffer")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(out.release)
+	<-done
+
+	require.NoError(t, writer.Close())
+	assert.Len(t, out.written(), 5)
+	assert.Zero(t, writer.Dropped())
+}
+
+// TestAsyncWriterFlushTimeout verifies Flush respects the context when the
+// output does not make progress.
+func TestAsyncWriterFlushTimeout(t *testing.T) {
+	t.Parallel()
+
+	out := &blockingWriter{release: make(chan struct{})}
+	writer := NewAsyncWriter(out, 4, OverflowDrop)
+	_, err := writer.Write([]byte("line\n"))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, writer.Flush(ctx), context.DeadlineExceeded)
+
+	close(out.release)
+	require.NoError(t, writer.Close())
+	assert.Len(t, out.written(), 1)
+}
+
+// TestConfigureAsync verifies the global logger writes through an async
+// buffer and Flush writes all pending lines. The test modifies the global
+// logger and must not run in parallel.
+func TestConfigureAsync(t *testing.T) {
+	t.Cleanup(func() {
+		require.NoError(t, Configure(Options{}))
+	})
+
+	var buf bytes.Buffer
+	require.NoError(t, Configure(Options{
+		Output:        &buf,
+		Format:        FormatLogfmt,
+		AsyncBuffer:   16,
+		AsyncOverflow: OverflowBlock,
+	}))
+
+	log.Error().Msg("async")
+	require.NoError(t, Flush(context.Background()))
+
+	// Reconfiguring closes the async writer, so buf is no longer written.
+	require.NoError(t, Configure(Options{}))
+	assert.True(t, strings.Contains(buf.String(), "message=async"), buf.String())
+	assert.Zero(t, Dropped())
+	assert.NoError(t, Flush(context.Background()))
+}
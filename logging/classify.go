@@ -0,0 +1,92 @@
+package logging
+
+import (
+	"bytes"
+	"regexp"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// LevelRule assigns a log level to all lines matching a pattern.
+type LevelRule struct {
+	// Pattern is matched against each line, without the trailing newline.
+	Pattern *regexp.Regexp
+	// Level is used for matching lines.
+	Level zerolog.Level
+}
+
+// DefaultLevelRules detects well-known messages of the standard library's
+// http server and common level prefixes like "[WARN]".
+var DefaultLevelRules = []LevelRule{
+	// Clients closing the connection during the handshake, e.g. load
+	// balancer health checks or port scanners.
+	{
+		Pattern: regexp.MustCompile(`^http: TLS handshake error from .*(EOF|connection reset by peer|broken pipe|i/o timeout)$`),
+		Level:   zerolog.DebugLevel,
+	},
+	{Pattern: regexp.MustCompile(`^http: TLS handshake error`), Level: zerolog.WarnLevel},
+	{Pattern: regexp.MustCompile(`^http: superfluous response\.WriteHeader`), Level: zerolog.WarnLevel},
+	{Pattern: regexp.MustCompile(`^http: response\.Write on hijacked connection`), Level: zerolog.WarnLevel},
+	{Pattern: regexp.MustCompile(`^http: URL query contains semicolon`), Level: zerolog.WarnLevel},
+	{Pattern: regexp.MustCompile(`(?i)^\[trace\]`), Level: zerolog.TraceLevel},
+	{Pattern: regexp.MustCompile(`(?i)^\[debug\]`), Level: zerolog.DebugLevel},
+	{Pattern: regexp.MustCompile(`(?i)^\[info\]`), Level: zerolog.InfoLevel},
+	{Pattern: regexp.MustCompile(`(?i)^\[warn(ing)?\]`), Level: zerolog.WarnLevel},
+	{Pattern: regexp.MustCompile(`(?i)^\[error\]`), Level: zerolog.ErrorLevel},
+}
+
+// ClassifyingWriter is an adapter between the standard library's log package
+// and zerolog. The level of each line is detected through a list of rules.
+type ClassifyingWriter struct {
+	// Rules are checked in order, the first matching rule defines the
+	// level.
+	Rules []LevelRule
+
+	// Fallback is the level used when no rule matches.
+	Fallback zerolog.Level
+
+	// Log starts a new event for the detected level.
+	// When nil, the global logger is used.
+	Log func(level zerolog.Level) *zerolog.Event
+}
+
+// NewClassifyingWriter creates a ClassifyingWriter using DefaultLevelRules
+// and the given fallback level.
+func NewClassifyingWriter(fallback zerolog.Level) *ClassifyingWriter {
+	return &ClassifyingWriter{
+		Rules:    DefaultLevelRules,
+		Fallback: fallback,
+	}
+}
+
+// Write logs the output of the standard library logger with the detected
+// level.
+func (w *ClassifyingWriter) Write(p []byte) (n int, err error) {
+	message := trimNewline(p)
+	level := w.Level(message)
+
+	if w.Log != nil {
+		w.Log(level).Msg(string(message))
+	} else {
+		log.WithLevel(level).Msg(string(message))
+	}
+	return len(p), nil
+}
+
+// Level returns the level of the first rule matching message, or the
+// fallback level.
+func (w *ClassifyingWriter) Level(message []byte) zerolog.Level {
+	for _, rule := range w.Rules {
+		if rule.Pattern.Match(message) {
+			return rule.Level
+		}
+	}
+	return w.Fallback
+}
+
+// trimNewline removes the trailing newline added by the standard library
+// logger.
+func trimNewline(p []byte) []byte {
+	return bytes.TrimRight(p, "\r\n")
+}
@@ -0,0 +1,90 @@
+package logging
+
+import (
+	"bytes"
+	golog "log"
+	"regexp"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestClassifyingWriterLevel verifies the level detection of the default
+// rules.
+func TestClassifyingWriterLevel(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// name identifies the test case.
+		name string
+		// message is the log line to classify.
+		message string
+		// want is the expected level.
+		want zerolog.Level
+	}{
+		{
+			name:    "handshake EOF",
+			message: "http: TLS handshake error from 10.0.0.1:4711: EOF",
+			want:    zerolog.DebugLevel,
+		},
+		{
+			name:    "handshake reset",
+			message: "http: TLS handshake error from 10.0.0.1:4711: read tcp: connection reset by peer",
+			want:    zerolog.DebugLevel,
+		},
+		{
+			name:    "handshake other",
+			message: "http: TLS handshake error from 10.0.0.1:4711: tls: unknown certificate",
+			want:    zerolog.WarnLevel,
+		},
+		{
+			name:    "superfluous WriteHeader",
+			message: "http: superfluous response.WriteHeader call from main.handler (main.go:12)",
+			want:    zerolog.WarnLevel,
+		},
+		{
+			name:    "warn prefix",
+			message: "[WARN] something odd",
+			want:    zerolog.WarnLevel,
+		},
+		{
+			name:    "lower case info prefix",
+			message: "[info] starting",
+			want:    zerolog.InfoLevel,
+		},
+		{
+			name:    "unknown uses fallback",
+			message: "http: Accept error: too many open files",
+			want:    zerolog.ErrorLevel,
+		},
+	}
+
+	writer := NewClassifyingWriter(zerolog.ErrorLevel)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, writer.Level([]byte(tt.message)))
+		})
+	}
+}
+
+// TestClassifyingWriterWrite verifies that custom rules are applied and the
+// trailing newline of the standard library logger is removed.
+func TestClassifyingWriterWrite(t *testing.T) {
+	t.Parallel()
+
+	output := &bytes.Buffer{}
+	logger := zerolog.New(output)
+
+	writer := &ClassifyingWriter{
+		Rules: []LevelRule{
+			{Pattern: regexp.MustCompile(`^noise`), Level: zerolog.DebugLevel},
+		},
+		Fallback: zerolog.ErrorLevel,
+		Log:      logger.WithLevel,
+	}
+
+	golog.New(writer, "", 0).Println("noise from a library")
+	assert.Equal(t, `{"severity":"DEBUG","message":"noise from a library"}`+"\n", output.String())
+}
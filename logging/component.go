@@ -0,0 +1,228 @@
+package logging
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rs/zerolog"
+)
+
+// ComponentFieldName is the field name holding the name of the component
+// that wrote a log event.
+const ComponentFieldName = "component"
+
+// ComponentLogger is a named logger with its own log level. Component names
+// are hierarchical and separated by dots, e.g. "httpserver.tls". A component
+// without a configured level uses the level of its closest configured
+// parent, or the global level set by SetLogLevel.
+type ComponentLogger struct {
+	// name is the dot separated name of the component.
+	name string
+	// logger is rebuilt whenever the output or the level changes.
+	logger atomic.Pointer[zerolog.Logger]
+}
+
+// levelRegistry tracks all component loggers and their configured levels.
+type levelRegistry struct {
+	// guard protects all fields during concurrent changes.
+	guard sync.Mutex
+	// base is the logger all component loggers are derived from.
+	base zerolog.Logger
+	// components holds all component loggers by name.
+	components map[string]*ComponentLogger
+	// configured holds the levels set through SetComponentLevel.
+	configured map[string]zerolog.Level
+}
+
+// rootFilterHook discards events of the global logger below the level set
+// by SetLogLevel. This is required as the global zerolog level is lowered to
+// the most verbose component level.
+type rootFilterHook struct{}
+
+var (
+	// registry holds the process-wide component loggers.
+	registry = &levelRegistry{
+		components: map[string]*ComponentLogger{},
+		configured: map[string]zerolog.Level{},
+	}
+
+	// rootLevel is the level set by SetLogLevel.
+	rootLevel atomic.Int32
+)
+
+// Component returns the logger for the given component name. Repeated calls
+// with the same name return the same logger.
+func Component(name string) *ComponentLogger {
+	registry.guard.Lock()
+	defer registry.guard.Unlock()
+
+	if component, ok := registry.components[name]; ok {
+		return component
+	}
+
+	component := &ComponentLogger{name: name}
+	registry.components[name] = component
+	registry.rebuild(component)
+	return component
+}
+
+// SetComponentLevel defines the log level of a component and all of its
+// children without a level of their own. See ParseLogLevel for the
+// supported values. An unknown value returns an error and keeps the current
+// level.
+func SetComponentLevel(name, logLevel string) error {
+	level, err := ParseLogLevel(logLevel)
+	if err != nil {
+		return err
+	}
+
+	registry.guard.Lock()
+	defer registry.guard.Unlock()
+
+	registry.configured[name] = level
+	registry.apply()
+	return nil
+}
+
+// ResetComponentLevel removes the level of a component set through
+// SetComponentLevel. The component uses the level of its closest configured
+// parent again.
+func ResetComponentLevel(name string) {
+	registry.guard.Lock()
+	defer registry.guard.Unlock()
+
+	delete(registry.configured, name)
+	registry.apply()
+}
+
+// ComponentLevels returns the names and levels of all components with a
+// configured level.
+func ComponentLevels() map[string]zerolog.Level {
+	registry.guard.Lock()
+	defer registry.guard.Unlock()
+
+	levels := make(map[string]zerolog.Level, len(registry.configured))
+	for name, level := range registry.configured {
+		levels[name] = level
+	}
+	return levels
+}
+
+// Name returns the name of the component.
+func (component *ComponentLogger) Name() string {
+	return component.name
+}
+
+// Logger returns the current zerolog logger of the component. Don't keep
+// the returned logger, as it is replaced when the level or output changes.
+func (component *ComponentLogger) Logger() *zerolog.Logger {
+	return component.logger.Load()
+}
+
+// Trace starts a new message with trace level.
+func (component *ComponentLogger) Trace() *zerolog.Event {
+	return component.Logger().Trace()
+}
+
+// Debug starts a new message with debug level.
+func (component *ComponentLogger) Debug() *zerolog.Event {
+	return component.Logger().Debug()
+}
+
+// Info starts a new message with info level.
+func (component *ComponentLogger) Info() *zerolog.Event {
+	return component.Logger().Info()
+}
+
+// Warn starts a new message with warn level.
+func (component *ComponentLogger) Warn() *zerolog.Event {
+	return component.Logger().Warn()
+}
+
+// Error starts a new message with error level.
+func (component *ComponentLogger) Error() *zerolog.Event {
+	return component.Logger().Error()
+}
+
+// WithLevel starts a new message with the given level.
+func (component *ComponentLogger) WithLevel(level zerolog.Level) *zerolog.Event {
+	return component.Logger().WithLevel(level)
+}
+
+// Run discards events below the root level.
+func (rootFilterHook) Run(event *zerolog.Event, level zerolog.Level, _ string) {
+	if level != zerolog.NoLevel && level < zerolog.Level(rootLevel.Load()) {
+		event.Discard()
+	}
+}
+
+// setRootLevel stores the level set by SetLogLevel and updates all derived
+// levels.
+func setRootLevel(level zerolog.Level) {
+	registry.guard.Lock()
+	defer registry.guard.Unlock()
+
+	rootLevel.Store(int32(level))
+	registry.apply()
+}
+
+// effectiveRootLevel returns the level the global logger currently logs at.
+// The global zerolog level still applies, as it might have been set
+// directly.
+func effectiveRootLevel() zerolog.Level {
+	return max(zerolog.Level(rootLevel.Load()), zerolog.GlobalLevel())
+}
+
+// setBase replaces the logger all component loggers are derived from.
+func (registry *levelRegistry) setBase(base zerolog.Logger) {
+	registry.guard.Lock()
+	defer registry.guard.Unlock()
+
+	registry.base = base
+	for _, component := range registry.components {
+		registry.rebuild(component)
+	}
+}
+
+// apply lowers the global zerolog level to the most verbose configured
+// level and rebuilds all component loggers. The caller must hold guard.
+func (registry *levelRegistry) apply() {
+	lowest := zerolog.Level(rootLevel.Load())
+	for _, level := range registry.configured {
+		lowest = min(lowest, level)
+	}
+	zerolog.SetGlobalLevel(lowest)
+
+	for _, component := range registry.components {
+		registry.rebuild(component)
+	}
+}
+
+// rebuild derives the logger of a component from the base logger. The
+// caller must hold guard.
+func (registry *levelRegistry) rebuild(component *ComponentLogger) {
+	logger := registry.base.With().
+		Str(ComponentFieldName, component.name).
+		Logger().
+		Level(registry.levelOf(component.name))
+	component.logger.Store(&logger)
+}
+
+// levelOf returns the configured level of a component or its closest
+// configured parent, falling back to the root level. The caller must hold
+// guard.
+func (registry *levelRegistry) levelOf(name string) zerolog.Level {
+	for len(name) > 0 {
+		if level, ok := registry.configured[name]; ok {
+			return level
+		}
+
+		index := strings.LastIndex(name, ".")
+		if index < 0 {
+			break
+		}
+		name = name[:index]
+	}
+	return zerolog.Level(rootLevel.Load())
+}
@@ -0,0 +1,102 @@
+package logging
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestComponentLevels verifies that component levels are inherited from
+// their closest configured parent and don't affect the global logger. The
+// test modifies the global logger and must not run in parallel.
+func TestComponentLevels(t *testing.T) {
+	output := &bytes.Buffer{}
+	require.NoError(t, Configure(Options{Output: output}))
+	t.Cleanup(func() {
+		registry.guard.Lock()
+		clear(registry.configured)
+		registry.guard.Unlock()
+		require.NoError(t, SetLogLevel("trace"))
+		require.NoError(t, Configure(Options{}))
+	})
+
+	require.NoError(t, SetLogLevel("info"))
+	require.NoError(t, SetComponentLevel("test", "debug"))
+	require.NoError(t, SetComponentLevel("test.quiet", "error"))
+	assert.Error(t, SetComponentLevel("test", "infp"))
+
+	assert.Same(t, Component("test.child"), Component("test.child"))
+	assert.Equal(t, "test.child", Component("test.child").Name())
+	assert.Equal(t, map[string]zerolog.Level{
+		"test":       zerolog.DebugLevel,
+		"test.quiet": zerolog.ErrorLevel,
+	}, ComponentLevels())
+
+	tests := []struct {
+		// name identifies the test case.
+		name string
+		// write writes a log event.
+		write func()
+		// wantLogged is whether the event is expected in the output.
+		wantLogged bool
+	}{
+		{
+			name:       "global debug is filtered",
+			write:      func() { log.Debug().Msg("event") },
+			wantLogged: false,
+		},
+		{
+			name:       "global info is logged",
+			write:      func() { log.Info().Msg("event") },
+			wantLogged: true,
+		},
+		{
+			name:       "component debug is logged",
+			write:      func() { Component("test").Debug().Msg("event") },
+			wantLogged: true,
+		},
+		{
+			name:       "child inherits parent level",
+			write:      func() { Component("test.child").Debug().Msg("event") },
+			wantLogged: true,
+		},
+		{
+			name:       "child trace is filtered",
+			write:      func() { Component("test.child").Trace().Msg("event") },
+			wantLogged: false,
+		},
+		{
+			name:       "child with own level",
+			write:      func() { Component("test.quiet").Warn().Msg("event") },
+			wantLogged: false,
+		},
+		{
+			name:       "unconfigured component uses global level",
+			write:      func() { Component("other").Debug().Msg("event") },
+			wantLogged: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output.Reset()
+			tt.write()
+			assert.Equal(t, tt.wantLogged, output.Len() > 0)
+		})
+	}
+
+	output.Reset()
+	Component("test").Info().Msg("event")
+	assert.Contains(t, output.String(), `"component":"test"`)
+	assert.Equal(t, zerolog.InfoLevel, GetLogLevel())
+
+	ResetComponentLevel("test.quiet")
+	assert.Equal(t, map[string]zerolog.Level{"test": zerolog.DebugLevel}, ComponentLevels())
+	output.Reset()
+	Component("test.quiet").Debug().Msg("event")
+	assert.Positive(t, output.Len())
+}
@@ -0,0 +1,249 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// Format defines how log events are encoded before they are written.
+type Format string
+
+const (
+	// FormatJSON writes one JSON object per line. This is the default and is
+	// understood by Google Cloud Logging.
+	FormatJSON Format = "json"
+	// FormatConsole writes human-readable, optionally colored lines meant
+	// for local development.
+	FormatConsole Format = "console"
+	// FormatLogfmt writes key=value pairs as understood by logfmt parsers.
+	FormatLogfmt Format = "logfmt"
+)
+
+const (
+	// TimeFormatUnix writes timestamps as seconds since the unix epoch.
+	// This is the default.
+	TimeFormatUnix = zerolog.TimeFormatUnix
+	// TimeFormatRFC3339Nano writes timestamps as RFC3339 strings with
+	// nanosecond precision.
+	TimeFormatRFC3339Nano = time.RFC3339Nano
+)
+
+// Options provides all available configuration options for the global
+// logger.
+type Options struct {
+	// Format defines the encoding of log events.
+	// Defaults to FormatJSON when left empty.
+	Format Format
+
+	// TimeFormat defines the layout of the timestamp field. Use one of the
+	// TimeFormat constants or any layout supported by the time package.
+	// Defaults to TimeFormatUnix when left empty.
+	TimeFormat string
+
+	// Output defines where log events are written to.
+	// Defaults to os.Stderr when nil.
+	Output io.Writer
+
+	// File writes log events to a rotating log file instead of Output when
+	// File.Path is set. The file is reopened on SIGHUP.
+	File FileOptions
+
+	// Caller adds the file and line of the log call to each event.
+	Caller bool
+
+	// SourceLocation adds the Google Cloud Logging source location of the
+	// log call to each event. This allows Error Reporting to link errors to
+	// the code that logged them.
+	SourceLocation bool
+
+	// AsyncBuffer enables asynchronous writes through an AsyncWriter that
+	// buffers up to the given number of lines. Log calls no longer wait for
+	// the output, use Flush before exiting the program.
+	// Writes are synchronous when left at 0.
+	AsyncBuffer int
+
+	// AsyncOverflow defines the behavior when the AsyncBuffer is full.
+	// Defaults to OverflowDrop.
+	AsyncOverflow OverflowPolicy
+
+	// DedupWindow enables a DedupWriter collapsing identical log lines
+	// written within the given duration into the first line and a summary
+	// with a repeat count.
+	// Deduplication is disabled when left at 0.
+	DedupWindow time.Duration
+}
+
+// ParseFormat converts a format name like "json", "console" or "logfmt" into
+// a Format. The name is matched case-insensitively.
+func ParseFormat(format string) (Format, error) {
+	switch Format(strings.ToLower(format)) {
+	case "", FormatJSON:
+		return FormatJSON, nil
+	case FormatConsole:
+		return FormatConsole, nil
+	case FormatLogfmt:
+		return FormatLogfmt, nil
+	default:
+		return FormatJSON, fmt.Errorf("unknown log format %q", format)
+	}
+}
+
+var (
+	// configureGuard serializes Configure and protects configured.
+	configureGuard sync.Mutex
+	// configured holds the options of the last successful call to
+	// Configure.
+	configured Options
+
+	// globalOutput is the writer of all loggers created by Configure. It is
+	// never replaced, so loggers copied from the global logger keep writing
+	// to the current output after Configure was called again.
+	globalOutput = &switchWriter{}
+)
+
+// switchWriter passes all writes to a replaceable writer.
+type switchWriter struct {
+	// guard protects out. It is held while writing, so out is not used
+	// anymore once replaced.
+	guard sync.RWMutex
+	// out receives all writes.
+	out io.Writer
+}
+
+// Write passes p to the current writer.
+func (w *switchWriter) Write(p []byte) (n int, err error) {
+	w.guard.RLock()
+	defer w.guard.RUnlock()
+	return w.out.Write(p)
+}
+
+// replace makes out the target of all following writes. Writes to the
+// previous writer have finished when replace returns.
+func (w *switchWriter) replace(out io.Writer) {
+	w.guard.Lock()
+	defer w.guard.Unlock()
+	w.out = out
+}
+
+// GetOptions returns the options of the last successful call to Configure.
+// Use it to change single options while keeping all others.
+func GetOptions() Options {
+	configureGuard.Lock()
+	defer configureGuard.Unlock()
+	return configured
+}
+
+// Configure replaces the global zerolog logger with one matching the given
+// options. Calling Configure with an empty Options struct restores the
+// default behavior of this package. The configuration is kept when an error
+// is returned.
+// Loggers derived from the global logger keep their fields and settings,
+// but write to the new output.
+func Configure(options Options) error {
+	configureGuard.Lock()
+	defer configureGuard.Unlock()
+
+	format, err := ParseFormat(string(options.Format))
+	if err != nil {
+		return err
+	}
+	options.Format = format
+
+	output := options.Output
+	if output == nil {
+		output = os.Stderr
+	}
+
+	var file *FileWriter
+	if len(options.File.Path) > 0 {
+		if file, err = NewFileWriter(options.File); err != nil {
+			return err
+		}
+		output = file
+	}
+
+	zerolog.TimeFieldFormat = options.TimeFormat
+
+	var writer io.Writer
+	switch format {
+	case FormatConsole:
+		writer = zerolog.ConsoleWriter{
+			Out:        output,
+			NoColor:    !isTerminal(output),
+			TimeFormat: consoleTimeFormat(options.TimeFormat),
+			// Severities don't match zerolog's level names, so they are
+			// printed as they are.
+			FormatLevel: func(severity any) string {
+				return fmt.Sprintf("%-9s", severity)
+			},
+		}
+	case FormatLogfmt:
+		writer = logfmtWriter{out: output}
+	default:
+		writer = output
+	}
+
+	var async *AsyncWriter
+	if options.AsyncBuffer > 0 {
+		// Formatting happens in the background as well.
+		async = NewAsyncWriter(writer, options.AsyncBuffer, options.AsyncOverflow)
+		writer = async
+	}
+
+	var dedup *DedupWriter
+	if options.DedupWindow > 0 {
+		// Lines are compared before they are formatted.
+		dedup = NewDedupWriter(writer, options.DedupWindow)
+		writer = dedup
+	}
+
+	context := zerolog.New(globalOutput).With().Timestamp()
+	if options.Caller {
+		context = context.Caller()
+	}
+
+	base := context.Logger()
+	if options.SourceLocation {
+		base = base.Hook(sourceLocationHook{})
+	}
+
+	globalOutput.replace(writer)
+	log.Logger = base.Hook(rootFilterHook{})
+	registry.setBase(base)
+	setDedupWriter(dedup)
+	setAsyncWriter(async)
+	setFileWriter(file)
+	configured = options
+	return nil
+}
+
+// consoleTimeFormat returns the layout used by the console writer to render
+// timestamps. Unix timestamps are not human-readable, so they are rendered
+// with the console writer's default layout instead.
+func consoleTimeFormat(timeFormat string) string {
+	if timeFormat == TimeFormatUnix {
+		return time.Kitchen
+	}
+	return timeFormat
+}
+
+// isTerminal reports whether writer is attached to a character device, i.e.
+// whether colored output can be displayed.
+func isTerminal(writer io.Writer) bool {
+	file, ok := writer.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,141 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseFormat verifies format names are matched case-insensitively and
+// unknown names are rejected.
+func TestParseFormat(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// name identifies the test case.
+		name string
+		// format is the format name to parse.
+		format string
+		// want is the expected format.
+		want Format
+		// wantErr is whether parsing should fail.
+		wantErr bool
+	}{
+		{name: "empty defaults to json", format: "", want: FormatJSON},
+		{name: "json", format: "json", want: FormatJSON},
+		{name: "console upper case", format: "CONSOLE", want: FormatConsole},
+		{name: "logfmt", format: "logfmt", want: FormatLogfmt},
+		{name: "unknown", format: "xml", want: FormatJSON, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseFormat(tt.format)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// TestConfigureFormats verifies the encoding of a log event for each
+// supported output format. The test modifies the global logger and must not
+// run in parallel.
+func TestConfigureFormats(t *testing.T) {
+	t.Cleanup(func() {
+		require.NoError(t, Configure(Options{}))
+	})
+
+	tests := []struct {
+		// name identifies the test case.
+		name string
+		// options is the logger configuration under test.
+		options Options
+		// want lists substrings expected in the output.
+		want []string
+	}{
+		{
+			name:    "json",
+			options: Options{Format: FormatJSON},
+			want:    []string{`"message":"hello world"`, `"key":"value"`},
+		},
+		{
+			name:    "logfmt",
+			options: Options{Format: FormatLogfmt},
+			want:    []string{`message="hello world"`, "key=value"},
+		},
+		{
+			name:    "console",
+			options: Options{Format: FormatConsole},
+			want:    []string{"hello world", "key=value"},
+		},
+		{
+			name:    "rfc3339 time format",
+			options: Options{TimeFormat: TimeFormatRFC3339Nano},
+			want:    []string{`"time":"20`},
+		},
+		{
+			name:    "caller",
+			options: Options{Caller: true},
+			want:    []string{`"caller":"`, "configure_test.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := &bytes.Buffer{}
+			tt.options.Output = output
+			require.NoError(t, Configure(tt.options))
+
+			log.Error().Str("key", "value").Msg("hello world")
+
+			line := output.String()
+			assert.True(t, strings.HasSuffix(line, "\n"))
+			for _, want := range tt.want {
+				assert.Contains(t, line, want)
+			}
+		})
+	}
+}
+
+// TestConfigureDerivedLoggers verifies loggers derived from the global
+// logger write to the output of the last Configure call, and GetOptions
+// returns the applied options. The test modifies the global logger and must
+// not run in parallel.
+func TestConfigureDerivedLoggers(t *testing.T) {
+	t.Cleanup(func() {
+		require.NoError(t, Configure(Options{}))
+	})
+
+	first := &bytes.Buffer{}
+	require.NoError(t, Configure(Options{Output: first, AsyncBuffer: 10}))
+	derived := log.With().Str("key", "value").Logger()
+
+	second := &bytes.Buffer{}
+	options := GetOptions()
+	options.Format = "LOGFMT"
+	options.Output = second
+	require.NoError(t, Configure(options))
+
+	assert.Equal(t, FormatLogfmt, GetOptions().Format)
+	assert.Equal(t, 10, GetOptions().AsyncBuffer)
+
+	derived.Error().Msg("hello world")
+	require.NoError(t, Flush(context.Background()))
+
+	assert.Empty(t, first.String())
+	assert.Contains(t, second.String(), "key=value")
+
+	assert.Error(t, Configure(Options{Format: "xml"}))
+	assert.Equal(t, FormatLogfmt, GetOptions().Format)
+}
@@ -0,0 +1,202 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// RepeatedFieldName is the field holding the number of suppressed lines in
+// the summary written by a DedupWriter.
+const RepeatedFieldName = "repeated"
+
+// DedupWriter collapses repeated log lines. Lines are considered identical
+// when all their fields except the timestamp match. The first
+// line passes through, identical lines within the following window are
+// suppressed. When the window ends, the last suppressed line is written with
+// an additional RepeatedFieldName field holding the number of suppressed
+// lines.
+// DedupWriter expects one JSON object per write, as produced by zerolog.
+type DedupWriter struct {
+	// out receives all lines that are not suppressed, and the summaries.
+	out io.Writer
+	// window is the duration in which identical lines are suppressed.
+	window time.Duration
+
+	// guard protects entries and closed.
+	guard sync.Mutex
+	// entries holds the suppression state for each key in its window.
+	entries map[string]*dedupEntry
+	// closed reports whether Close has been called.
+	closed bool
+}
+
+// dedupEntry is the suppression state for a single key.
+type dedupEntry struct {
+	// timer ends the window of the entry.
+	timer *time.Timer
+	// last is a copy of the last suppressed line.
+	last []byte
+	// repeated is the number of suppressed lines.
+	repeated int
+}
+
+// NewDedupWriter creates a DedupWriter writing to out, suppressing identical
+// lines for the given window.
+func NewDedupWriter(out io.Writer, window time.Duration) *DedupWriter {
+	return &DedupWriter{
+		out:     out,
+		window:  window,
+		entries: make(map[string]*dedupEntry),
+	}
+}
+
+// Write passes p to the output unless an identical line was written within
+// the window. Lines that are not valid JSON objects are never suppressed.
+func (w *DedupWriter) Write(p []byte) (n int, err error) {
+	key, ok := dedupKey(p)
+	if !ok {
+		return w.out.Write(p)
+	}
+
+	w.guard.Lock()
+	if w.closed {
+		w.guard.Unlock()
+		return w.out.Write(p)
+	}
+
+	if entry, exists := w.entries[key]; exists {
+		// The caller may reuse p after Write returns.
+		entry.last = append(entry.last[:0], p...)
+		entry.repeated++
+		w.guard.Unlock()
+		return len(p), nil
+	}
+
+	entry := &dedupEntry{}
+	entry.timer = time.AfterFunc(w.window, func() { w.expire(key, entry) })
+	w.entries[key] = entry
+	w.guard.Unlock()
+
+	return w.out.Write(p)
+}
+
+// Flush writes the summaries of all open windows and starts new windows for
+// the following lines.
+func (w *DedupWriter) Flush() {
+	w.guard.Lock()
+	entries := w.entries
+	w.entries = make(map[string]*dedupEntry)
+	w.guard.Unlock()
+
+	for _, entry := range entries {
+		entry.timer.Stop()
+		w.writeSummary(entry)
+	}
+}
+
+// Close writes the summaries of all open windows. Lines written after Close
+// are passed to the output directly.
+func (w *DedupWriter) Close() error {
+	w.guard.Lock()
+	w.closed = true
+	w.guard.Unlock()
+
+	w.Flush()
+	return nil
+}
+
+// expire ends the window of entry and writes its summary. Entries already
+// removed by Flush are ignored.
+func (w *DedupWriter) expire(key string, entry *dedupEntry) {
+	w.guard.Lock()
+	current := w.entries[key] == entry
+	if current {
+		delete(w.entries, key)
+	}
+	w.guard.Unlock()
+
+	if current {
+		w.writeSummary(entry)
+	}
+}
+
+// writeSummary writes the last suppressed line of entry with the number of
+// suppressed lines. Nothing is written when no line was suppressed.
+func (w *DedupWriter) writeSummary(entry *dedupEntry) {
+	if entry.repeated == 0 {
+		return
+	}
+
+	line := bytes.TrimRight(entry.last, " \r\n")
+	line = bytes.TrimSuffix(line, []byte("}"))
+
+	summary := make([]byte, 0, len(line)+len(RepeatedFieldName)+16)
+	summary = append(summary, line...)
+	if !bytes.HasSuffix(bytes.TrimSpace(line), []byte("{")) {
+		summary = append(summary, ',')
+	}
+	summary = append(summary, `"`+RepeatedFieldName+`":`...)
+	summary = strconv.AppendInt(summary, int64(entry.repeated), 10)
+	summary = append(summary, "}\n"...)
+
+	// Errors can't be reported, as this is the log output itself.
+	_, _ = w.out.Write(summary)
+}
+
+// dedupKey returns the key identifying identical lines, built from all
+// fields except the timestamp. Returns false when p is not a JSON object.
+func dedupKey(p []byte) (string, bool) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(p, &fields); err != nil {
+		return "", false
+	}
+	delete(fields, zerolog.TimestampFieldName)
+
+	var key bytes.Buffer
+	for _, name := range slices.Sorted(maps.Keys(fields)) {
+		key.WriteString(name)
+		key.WriteByte(0)
+		key.Write(fields[name])
+		key.WriteByte(0)
+	}
+	return key.String(), true
+}
+
+var (
+	// dedupGuard protects activeDedup.
+	dedupGuard sync.Mutex
+	// activeDedup is the DedupWriter used by the global logger, if any.
+	activeDedup *DedupWriter
+)
+
+// flushDedup writes the summaries of all open windows of the global logger.
+func flushDedup() {
+	dedupGuard.Lock()
+	dedup := activeDedup
+	dedupGuard.Unlock()
+
+	if dedup != nil {
+		dedup.Flush()
+	}
+}
+
+// setDedupWriter replaces the DedupWriter of the global logger. The previous
+// writer is closed after writing its summaries.
+func setDedupWriter(dedup *DedupWriter) {
+	dedupGuard.Lock()
+	previous := activeDedup
+	activeDedup = dedup
+	dedupGuard.Unlock()
+
+	if previous != nil {
+		_ = previous.Close()
+	}
+}
@@ -0,0 +1,179 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lockedBuffer is a bytes.Buffer safe for concurrent use.
+type lockedBuffer struct {
+	// guard protects buf.
+	guard sync.Mutex
+	// buf holds all written bytes.
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.guard.Lock()
+	defer b.guard.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) lines() []string {
+	b.guard.Lock()
+	defer b.guard.Unlock()
+	return strings.Split(strings.TrimSpace(b.buf.String()), "\n")
+}
+
+// TestDedupWriter verifies identical lines are collapsed into the first line
+// and a summary, while different lines pass through.
+func TestDedupWriter(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// name identifies the test case.
+		name string
+		// lines are written in order.
+		lines []string
+		// want are the expected output lines after flushing.
+		want []string
+	}{
+		{
+			name:  "single line",
+			lines: []string{`{"severity":"ERROR","message":"down"}`},
+			want:  []string{`{"severity":"ERROR","message":"down"}`},
+		},
+		{
+			name: "repeated line",
+			lines: []string{
+				`{"severity":"ERROR","message":"down","time":1}`,
+				`{"severity":"ERROR","message":"down","time":2}`,
+				`{"severity":"ERROR","message":"down","time":3}`,
+			},
+			want: []string{
+				`{"severity":"ERROR","message":"down","time":1}`,
+				`{"severity":"ERROR","message":"down","time":3,"repeated":2}`,
+			},
+		},
+		{
+			name: "different errors",
+			lines: []string{
+				`{"severity":"ERROR","error":"a","message":"down"}`,
+				`{"severity":"ERROR","error":"b","message":"down"}`,
+			},
+			want: []string{
+				`{"severity":"ERROR","error":"a","message":"down"}`,
+				`{"severity":"ERROR","error":"b","message":"down"}`,
+			},
+		},
+		{
+			name: "different components",
+			lines: []string{
+				`{"severity":"ERROR","component":"a","message":"down"}`,
+				`{"severity":"ERROR","component":"b","message":"down"}`,
+			},
+			want: []string{
+				`{"severity":"ERROR","component":"a","message":"down"}`,
+				`{"severity":"ERROR","component":"b","message":"down"}`,
+			},
+		},
+		{
+			name: "different fields",
+			lines: []string{
+				`{"severity":"INFO","component":"httpserver.accesslog","path":"/a","status":200,"time":1}`,
+				`{"severity":"INFO","component":"httpserver.accesslog","path":"/b","status":404,"time":2}`,
+				`{"severity":"INFO","component":"httpserver.accesslog","path":"/a","status":500,"time":3}`,
+				`{"severity":"INFO","component":"httpserver.accesslog","status":200,"path":"/a","time":4}`,
+			},
+			want: []string{
+				`{"severity":"INFO","component":"httpserver.accesslog","path":"/a","status":200,"time":1}`,
+				`{"severity":"INFO","component":"httpserver.accesslog","path":"/b","status":404,"time":2}`,
+				`{"severity":"INFO","component":"httpserver.accesslog","path":"/a","status":500,"time":3}`,
+				`{"severity":"INFO","component":"httpserver.accesslog","status":200,"path":"/a","time":4,"repeated":1}`,
+			},
+		},
+		{
+			name:  "not json",
+			lines: []string{"plain", "plain"},
+			want:  []string{"plain", "plain"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var out lockedBuffer
+			writer := NewDedupWriter(&out, time.Hour)
+			for _, line := range tt.lines {
+				_, err := writer.Write([]byte(line + "\n"))
+				require.NoError(t, err)
+			}
+			require.NoError(t, writer.Close())
+
+			assert.Equal(t, tt.want, out.lines())
+		})
+	}
+}
+
+// TestDedupWriterWindow verifies the summary is written when the window
+// ends and the next identical line passes through again.
+func TestDedupWriterWindow(t *testing.T) {
+	t.Parallel()
+
+	var out lockedBuffer
+	writer := NewDedupWriter(&out, 10*time.Millisecond)
+	line := []byte(`{"message":"down"}` + "\n")
+
+	for i := 0; i < 3; i++ {
+		_, err := writer.Write(line)
+		require.NoError(t, err)
+	}
+
+	assert.Eventually(t, func() bool {
+		return len(out.lines()) == 2
+	}, time.Second, time.Millisecond)
+
+	_, err := writer.Write(line)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	assert.Equal(t, []string{
+		`{"message":"down"}`,
+		`{"message":"down","repeated":2}`,
+		`{"message":"down"}`,
+	}, out.lines())
+}
+
+// TestConfigureDedup verifies the global logger collapses repeated lines
+// and Flush writes the summary. The test modifies the global logger and must
+// not run in parallel.
+func TestConfigureDedup(t *testing.T) {
+	t.Cleanup(func() {
+		require.NoError(t, Configure(Options{}))
+	})
+
+	var out lockedBuffer
+	require.NoError(t, Configure(Options{
+		Output:      &out,
+		Format:      FormatLogfmt,
+		DedupWindow: time.Hour,
+	}))
+
+	for i := 0; i < 5; i++ {
+		log.Error().Msg("down")
+	}
+	require.NoError(t, Flush(context.Background()))
+
+	lines := out.lines()
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[1], "repeated=4")
+}
@@ -0,0 +1,413 @@
+package logging
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// backupTimeFormat is the timestamp layout appended to rotated files.
+const backupTimeFormat = "20060102T150405.000"
+
+// FileOptions configures a FileWriter.
+type FileOptions struct {
+	// Path of the log file. Missing directories are created.
+	Path string
+
+	// MaxSize rotates the file before it grows beyond the given number of
+	// bytes. Size based rotation is disabled when left at 0.
+	MaxSize int64
+
+	// MaxAge rotates the file when it has been written to for longer than
+	// the given duration. Age based rotation is disabled when left at 0.
+	MaxAge time.Duration
+
+	// MaxBackups defines how many rotated files are kept. Older files are
+	// deleted. All files are kept when left at 0.
+	MaxBackups int
+
+	// Retention deletes rotated files older than the given duration.
+	// Files are kept regardless of their age when left at 0.
+	Retention time.Duration
+
+	// Compress compresses rotated files with gzip.
+	Compress bool
+}
+
+// FileWriter writes log lines to a file and rotates it based on size and
+// age. Rotated files are renamed by appending a timestamp to the file name,
+// followed by a counter when the name is already taken.
+type FileWriter struct {
+	// options holds the rotation settings.
+	options FileOptions
+
+	// guard protects file, size, opened and closed.
+	guard sync.Mutex
+	// file is the currently open log file.
+	file *os.File
+	// closed reports whether Close has been called.
+	closed bool
+	// size is the number of bytes in file.
+	size int64
+	// opened is the time file has been opened or rotated.
+	opened time.Time
+
+	// cleanupGuard serializes compressing and deleting rotated files.
+	cleanupGuard sync.Mutex
+}
+
+// NewFileWriter opens the log file for appending and returns a FileWriter
+// rotating it according to options.
+func NewFileWriter(options FileOptions) (*FileWriter, error) {
+	if len(options.Path) == 0 {
+		return nil, errors.New("log file path is empty")
+	}
+
+	writer := &FileWriter{options: options}
+	if err := writer.open(); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+// Write appends p to the log file, rotating the file first when required.
+func (w *FileWriter) Write(p []byte) (n int, err error) {
+	w.guard.Lock()
+	defer w.guard.Unlock()
+
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	if w.needsRotation(len(p)) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err = w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate renames the current log file and opens a new one.
+func (w *FileWriter) Rotate() error {
+	w.guard.Lock()
+	defer w.guard.Unlock()
+
+	return w.rotate()
+}
+
+// Reopen closes and reopens the log file without rotating it. Use this after
+// an external tool like logrotate moved the file. Does nothing once the
+// writer is closed.
+func (w *FileWriter) Reopen() error {
+	w.guard.Lock()
+	defer w.guard.Unlock()
+
+	if w.closed {
+		return nil
+	}
+
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return err
+		}
+		w.file = nil
+	}
+	return w.open()
+}
+
+// Close closes the log file. Following writes fail.
+func (w *FileWriter) Close() error {
+	w.guard.Lock()
+	defer w.guard.Unlock()
+
+	w.closed = true
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// needsRotation reports whether the file has to be rotated before writing
+// the given number of bytes. Must be called with guard held.
+func (w *FileWriter) needsRotation(length int) bool {
+	if w.options.MaxSize > 0 && w.size > 0 && w.size+int64(length) > w.options.MaxSize {
+		return true
+	}
+	return w.options.MaxAge > 0 && time.Since(w.opened) >= w.options.MaxAge
+}
+
+// open opens the log file for appending. Must be called with guard held.
+func (w *FileWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.options.Path), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(w.options.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	w.file = file
+	w.size = info.Size()
+	w.opened = time.Now()
+	return nil
+}
+
+// rotate renames the current log file, opens a new one and cleans up old
+// files in the background. Must be called with guard held.
+func (w *FileWriter) rotate() error {
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return err
+		}
+		w.file = nil
+	}
+
+	backup, err := backupPath(w.options.Path + "." + time.Now().Format(backupTimeFormat))
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(w.options.Path, backup); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	go w.cleanup()
+	return nil
+}
+
+// backupPath returns base, or base with the lowest free counter suffix when
+// a rotated file, compressed or not, already uses the name. This happens
+// when the file is rotated more than once within a millisecond.
+func backupPath(base string) (string, error) {
+	path := base
+	for sequence := 1; ; sequence++ {
+		taken, err := anyExists(path, path+".gz")
+		if err != nil || !taken {
+			return path, err
+		}
+		path = base + "-" + strconv.Itoa(sequence)
+	}
+}
+
+// anyExists reports whether any of paths exists.
+func anyExists(paths ...string) (bool, error) {
+	for _, path := range paths {
+		_, err := os.Lstat(path)
+		switch {
+		case err == nil:
+			return true, nil
+		case !os.IsNotExist(err):
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// cleanup compresses rotated files and deletes files exceeding MaxBackups or
+// Retention. Errors are written to stderr, as the log itself is affected.
+func (w *FileWriter) cleanup() {
+	w.cleanupGuard.Lock()
+	defer w.cleanupGuard.Unlock()
+
+	backups, err := w.backups()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to list rotated log files: %v\n", err)
+		return
+	}
+
+	for i, backup := range backups {
+		expired := w.options.MaxBackups > 0 && i >= w.options.MaxBackups
+		if w.options.Retention > 0 && time.Since(backup.rotated) > w.options.Retention {
+			expired = true
+		}
+
+		switch {
+		case expired:
+			err = os.Remove(backup.path)
+		case w.options.Compress && !strings.HasSuffix(backup.path, ".gz"):
+			err = compressFile(backup.path)
+		default:
+			continue
+		}
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to clean up rotated log file %s: %v\n", backup.path, err)
+		}
+	}
+}
+
+// rotatedFile is a log file renamed by rotate.
+type rotatedFile struct {
+	// path of the rotated file.
+	path string
+	// rotated is the time of the rotation, taken from the file name.
+	rotated time.Time
+	// sequence is the counter suffix of files rotated within the same
+	// millisecond, zero for the first one.
+	sequence int
+}
+
+// backups returns all rotated files, newest first.
+func (w *FileWriter) backups() ([]rotatedFile, error) {
+	prefix := filepath.Base(w.options.Path) + "."
+	entries, err := os.ReadDir(filepath.Dir(w.options.Path))
+	if err != nil {
+		return nil, err
+	}
+
+	backups := []rotatedFile{}
+	for _, entry := range entries {
+		timestamp, found := strings.CutPrefix(entry.Name(), prefix)
+		if !found || entry.IsDir() {
+			continue
+		}
+
+		timestamp = strings.TrimSuffix(timestamp, ".gz")
+		sequence := 0
+		if before, counter, found := strings.Cut(timestamp, "-"); found {
+			if sequence, err = strconv.Atoi(counter); err != nil {
+				continue
+			}
+			timestamp = before
+		}
+
+		rotated, err := time.ParseInLocation(backupTimeFormat, timestamp, time.Local)
+		if err != nil {
+			continue
+		}
+
+		backups = append(backups, rotatedFile{
+			path:     filepath.Join(filepath.Dir(w.options.Path), entry.Name()),
+			rotated:  rotated,
+			sequence: sequence,
+		})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].rotated.Equal(backups[j].rotated) {
+			return backups[i].sequence > backups[j].sequence
+		}
+		return backups[i].rotated.After(backups[j].rotated)
+	})
+	return backups, nil
+}
+
+// compressFile replaces path with a gzip compressed copy named path.gz.
+func compressFile(path string) error {
+	source, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	target, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	compressor := gzip.NewWriter(target)
+	if _, err := io.Copy(compressor, source); err != nil {
+		_ = target.Close()
+		_ = os.Remove(path + ".gz")
+		return err
+	}
+	if err := errors.Join(compressor.Close(), target.Close()); err != nil {
+		_ = os.Remove(path + ".gz")
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+var (
+	// fileGuard protects activeFile and stopReopen.
+	fileGuard sync.Mutex
+	// activeFile is the FileWriter used by the global logger, if any.
+	activeFile *FileWriter
+	// stopReopen stops the SIGHUP handler of activeFile.
+	stopReopen func()
+)
+
+// Reopen reopens the log file of the global logger, see FileWriter.Reopen.
+// This is done automatically on SIGHUP. Reopen does nothing when the global
+// logger does not write to a file.
+func Reopen() error {
+	fileGuard.Lock()
+	file := activeFile
+	fileGuard.Unlock()
+
+	if file == nil {
+		return nil
+	}
+	return file.Reopen()
+}
+
+// setFileWriter replaces the FileWriter of the global logger and reopens
+// file on SIGHUP. The previous writer is closed.
+func setFileWriter(file *FileWriter) {
+	fileGuard.Lock()
+	previous, stopPrevious := activeFile, stopReopen
+	activeFile, stopReopen = file, nil
+	if file != nil {
+		stopReopen = reopenOnSignal(file)
+	}
+	fileGuard.Unlock()
+
+	if stopPrevious != nil {
+		stopPrevious()
+	}
+	if previous != nil {
+		_ = previous.Close()
+	}
+}
+
+// reopenOnSignal reopens file whenever SIGHUP is received, until the
+// returned function is called.
+func reopenOnSignal(file *FileWriter) func() {
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, syscall.SIGHUP)
+
+	go func() {
+		for {
+			select {
+			case <-signals:
+				if err := file.Reopen(); err != nil {
+					fmt.Fprintf(os.Stderr, "Failed to reopen log file: %v\n", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
@@ -0,0 +1,227 @@
+package logging
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rotatedFiles returns the names of all rotated files next to path.
+func rotatedFiles(t *testing.T, path string) []string {
+	t.Helper()
+
+	matches, err := filepath.Glob(path + ".*")
+	require.NoError(t, err)
+	return matches
+}
+
+// TestFileWriterRotateBySize verifies the file is rotated before it grows
+// beyond MaxSize and old files are deleted beyond MaxBackups.
+func TestFileWriterRotateBySize(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "logs", "app.log")
+	writer, err := NewFileWriter(FileOptions{
+		Path:       path,
+		MaxSize:    10,
+		MaxBackups: 1,
+	})
+	require.NoError(t, err)
+
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		_, err := writer.Write([]byte(line))
+		require.NoError(t, err)
+		// Keep backup names unique.
+		time.Sleep(2 * time.Millisecond)
+	}
+	require.NoError(t, writer.Close())
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "third\n", string(content))
+
+	assert.Eventually(t, func() bool {
+		return len(rotatedFiles(t, path)) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	backup, err := os.ReadFile(rotatedFiles(t, path)[0])
+	require.NoError(t, err)
+	assert.Equal(t, "second\n", string(backup))
+}
+
+// TestFileWriterRotateByAge verifies the file is rotated once it has been
+// open longer than MaxAge.
+func TestFileWriterRotateByAge(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	writer, err := NewFileWriter(FileOptions{
+		Path:   path,
+		MaxAge: 10 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	_, err = writer.Write([]byte("first\n"))
+	require.NoError(t, err)
+	time.Sleep(20 * time.Millisecond)
+	_, err = writer.Write([]byte("second\n"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "second\n", string(content))
+	assert.Len(t, rotatedFiles(t, path), 1)
+}
+
+// TestFileWriterCompress verifies rotated files are compressed.
+func TestFileWriterCompress(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	writer, err := NewFileWriter(FileOptions{Path: path, Compress: true})
+	require.NoError(t, err)
+
+	_, err = writer.Write([]byte("rotated\n"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Rotate())
+	require.NoError(t, writer.Close())
+
+	var backups []string
+	assert.Eventually(t, func() bool {
+		backups = rotatedFiles(t, path)
+		return len(backups) == 1 && strings.HasSuffix(backups[0], ".gz")
+	}, time.Second, 5*time.Millisecond)
+
+	file, err := os.Open(backups[0])
+	require.NoError(t, err)
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	require.NoError(t, err)
+	content, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "rotated\n", string(content))
+}
+
+// TestFileWriterRotateSameTime verifies rotations within the same
+// millisecond keep all rotated files and MaxBackups keeps the newest ones.
+func TestFileWriterRotateSameTime(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	writer, err := NewFileWriter(FileOptions{Path: path, MaxBackups: 2})
+	require.NoError(t, err)
+
+	// The timestamp of a rotated file is taken again by rotations of the
+	// same millisecond.
+	taken := path + "." + time.Now().Format(backupTimeFormat)
+	backup, err := backupPath(taken)
+	require.NoError(t, err)
+	assert.Equal(t, taken, backup)
+	require.NoError(t, os.WriteFile(taken+".gz", nil, 0o644))
+	require.NoError(t, os.WriteFile(taken+"-1", nil, 0o644))
+	backup, err = backupPath(taken)
+	require.NoError(t, err)
+	assert.Equal(t, taken+"-2", backup)
+	require.NoError(t, os.Remove(taken+".gz"))
+	require.NoError(t, os.Remove(taken+"-1"))
+
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		_, err := writer.Write([]byte(line))
+		require.NoError(t, err)
+		require.NoError(t, writer.Rotate())
+	}
+	require.NoError(t, writer.Close())
+
+	assert.Eventually(t, func() bool {
+		return len(rotatedFiles(t, path)) == 2
+	}, time.Second, 5*time.Millisecond)
+
+	backups, err := writer.backups()
+	require.NoError(t, err)
+	contents := []string{}
+	for _, backup := range backups {
+		content, err := os.ReadFile(backup.path)
+		require.NoError(t, err)
+		contents = append(contents, string(content))
+	}
+	assert.Equal(t, []string{"third\n", "second\n"}, contents)
+}
+
+// TestFileWriterRetention verifies rotated files older than Retention are
+// deleted.
+func TestFileWriterRetention(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	expired := path + "." + time.Now().Add(-2*time.Hour).Format(backupTimeFormat)
+	require.NoError(t, os.WriteFile(expired, []byte("old\n"), 0o644))
+
+	writer, err := NewFileWriter(FileOptions{Path: path, Retention: time.Hour})
+	require.NoError(t, err)
+	require.NoError(t, writer.Rotate())
+	require.NoError(t, writer.Close())
+
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(expired)
+		return os.IsNotExist(err)
+	}, time.Second, 5*time.Millisecond)
+}
+
+// TestFileWriterReopen verifies Reopen continues writing to a new file after
+// the old one has been moved.
+func TestFileWriterReopen(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	writer, err := NewFileWriter(FileOptions{Path: path})
+	require.NoError(t, err)
+
+	_, err = writer.Write([]byte("before\n"))
+	require.NoError(t, err)
+	require.NoError(t, os.Rename(path, path+".moved"))
+	require.NoError(t, writer.Reopen())
+	_, err = writer.Write([]byte("after\n"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "after\n", string(content))
+
+	_, err = writer.Write([]byte("closed\n"))
+	assert.ErrorIs(t, err, os.ErrClosed)
+
+	require.NoError(t, writer.Reopen())
+	_, err = writer.Write([]byte("reopened\n"))
+	assert.ErrorIs(t, err, os.ErrClosed)
+}
+
+// TestConfigureFile verifies the global logger writes to the configured
+// file. The test modifies the global logger and must not run in parallel.
+func TestConfigureFile(t *testing.T) {
+	t.Cleanup(func() {
+		require.NoError(t, Configure(Options{}))
+	})
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, Configure(Options{File: FileOptions{Path: path}}))
+
+	log.Error().Msg("to file")
+	require.NoError(t, Reopen())
+	require.NoError(t, Configure(Options{}))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `"message":"to file"`)
+	assert.NoError(t, Reopen())
+}
@@ -1,6 +1,8 @@
 package logging
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/rs/zerolog"
@@ -22,17 +24,23 @@ type NullWriter struct{}
 func init() {
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
 	zerolog.LevelFieldName = "severity"
+	zerolog.LevelFieldMarshalFunc = Severity
+
+	// Don't filter anything until SetLogLevel is called, so that the global
+	// zerolog level is respected.
+	rootLevel.Store(int32(zerolog.TraceLevel))
+	_ = Configure(Options{})
 }
 
 // Write logs the output of the standard library logger as an error.
 func (w ErrorLogWriter) Write(p []byte) (n int, err error) {
-	log.Error().Msg(string(p))
+	log.Error().Msg(string(trimNewline(p)))
 	return len(p), nil
 }
 
 // Write logs the output of the standard library logger as debug.
 func (w DebugLogWriter) Write(p []byte) (n int, err error) {
-	log.Debug().Msg(string(p))
+	log.Debug().Msg(string(trimNewline(p)))
 	return len(p), nil
 }
 
@@ -42,17 +50,54 @@ func (w NullWriter) Write(p []byte) (n int, err error) {
 }
 
 // SetLogLevel defines the zerolog level based on commonly used loglevel strings.
-func SetLogLevel(logLevel string) {
-	switch strings.ToLower(logLevel) {
-	default:
-		fallthrough
+// See ParseLogLevel for the supported values. An unknown value returns an
+// error and keeps the current level.
+// Components with a level set through SetComponentLevel keep their level.
+func SetLogLevel(logLevel string) error {
+	level, err := ParseLogLevel(logLevel)
+	if err != nil {
+		return err
+	}
+
+	setRootLevel(level)
+	return nil
+}
+
+// GetLogLevel returns the current level of the global logger.
+func GetLogLevel() zerolog.Level {
+	return effectiveRootLevel()
+}
+
+// ParseLogLevel converts commonly used loglevel strings into a zerolog level.
+// Supported are trace, debug, info, warn/warning, error/critical, fatal,
+// panic and off/disabled, as well as zerolog's numeric levels.
+// Names are matched case-insensitively.
+func ParseLogLevel(logLevel string) (zerolog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(logLevel)) {
+	case "trace":
+		return zerolog.TraceLevel, nil
 	case "debug":
-		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+		return zerolog.DebugLevel, nil
 	case "info":
-		zerolog.SetGlobalLevel(zerolog.InfoLevel)
+		return zerolog.InfoLevel, nil
 	case "warn", "warning":
-		zerolog.SetGlobalLevel(zerolog.WarnLevel)
+		return zerolog.WarnLevel, nil
 	case "error", "critical":
-		zerolog.SetGlobalLevel(zerolog.ErrorLevel)
+		return zerolog.ErrorLevel, nil
+	case "fatal":
+		return zerolog.FatalLevel, nil
+	case "panic":
+		return zerolog.PanicLevel, nil
+	case "off", "disabled":
+		return zerolog.Disabled, nil
 	}
+
+	if number, err := strconv.Atoi(strings.TrimSpace(logLevel)); err == nil {
+		level := zerolog.Level(number)
+		if (level >= zerolog.TraceLevel && level <= zerolog.PanicLevel) || level == zerolog.Disabled {
+			return level, nil
+		}
+	}
+
+	return zerolog.NoLevel, fmt.Errorf("unknown log level %q", logLevel)
 }
@@ -0,0 +1,69 @@
+package logging
+
+import (
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseLogLevel verifies supported level names, aliases, numeric levels
+// and the rejection of unknown values.
+func TestParseLogLevel(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// name identifies the test case.
+		name string
+		// logLevel is the level string to parse.
+		logLevel string
+		// want is the expected zerolog level.
+		want zerolog.Level
+		// wantErr is whether parsing should fail.
+		wantErr bool
+	}{
+		{name: "trace", logLevel: "trace", want: zerolog.TraceLevel},
+		{name: "debug upper case", logLevel: "DEBUG", want: zerolog.DebugLevel},
+		{name: "info", logLevel: "info", want: zerolog.InfoLevel},
+		{name: "warning alias", logLevel: "warning", want: zerolog.WarnLevel},
+		{name: "critical alias", logLevel: "critical", want: zerolog.ErrorLevel},
+		{name: "fatal", logLevel: "fatal", want: zerolog.FatalLevel},
+		{name: "panic", logLevel: "panic", want: zerolog.PanicLevel},
+		{name: "off", logLevel: "off", want: zerolog.Disabled},
+		{name: "disabled", logLevel: "disabled", want: zerolog.Disabled},
+		{name: "numeric", logLevel: "2", want: zerolog.WarnLevel},
+		{name: "numeric trace", logLevel: "-1", want: zerolog.TraceLevel},
+		{name: "numeric out of range", logLevel: "6", want: zerolog.NoLevel, wantErr: true},
+		{name: "typo", logLevel: "infp", want: zerolog.NoLevel, wantErr: true},
+		{name: "empty", logLevel: "", want: zerolog.NoLevel, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseLogLevel(tt.logLevel)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// TestSetLogLevel verifies that unknown values keep the current level. The
+// test modifies the global level and must not run in parallel.
+func TestSetLogLevel(t *testing.T) {
+	t.Cleanup(func() {
+		require.NoError(t, SetLogLevel("trace"))
+	})
+
+	require.NoError(t, SetLogLevel("warn"))
+	assert.Equal(t, zerolog.WarnLevel, GetLogLevel())
+
+	assert.Error(t, SetLogLevel("infp"))
+	assert.Equal(t, zerolog.WarnLevel, GetLogLevel())
+}
@@ -0,0 +1,93 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// logfmtWriter converts the JSON events written by zerolog into logfmt
+// lines.
+type logfmtWriter struct {
+	// out is the writer receiving the converted lines.
+	out io.Writer
+}
+
+// Write converts one JSON encoded event into a logfmt line. Events that
+// cannot be decoded are passed through unchanged.
+func (w logfmtWriter) Write(p []byte) (n int, err error) {
+	decoder := json.NewDecoder(bytes.NewReader(p))
+	decoder.UseNumber()
+
+	var event map[string]any
+	if err := decoder.Decode(&event); err != nil {
+		return w.out.Write(p)
+	}
+
+	// Well-known fields go first, everything else is sorted by name to get
+	// a stable output.
+	leading := []string{
+		zerolog.TimestampFieldName,
+		zerolog.LevelFieldName,
+		zerolog.MessageFieldName,
+	}
+
+	keys := make([]string, 0, len(event))
+	for key := range event {
+		if !slices.Contains(leading, key) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	line := &bytes.Buffer{}
+	for _, key := range append(leading, keys...) {
+		value, ok := event[key]
+		if !ok {
+			continue
+		}
+		if line.Len() > 0 {
+			line.WriteByte(' ')
+		}
+		line.WriteString(key)
+		line.WriteByte('=')
+		line.WriteString(logfmtValue(value))
+	}
+	line.WriteByte('\n')
+
+	if _, err := w.out.Write(line.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// logfmtValue renders a decoded JSON value as a logfmt value, quoting it when
+// required.
+func logfmtValue(value any) string {
+	var text string
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		text = v
+	case json.Number, bool:
+		return fmt.Sprint(v)
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			text = fmt.Sprint(v)
+		} else {
+			text = string(encoded)
+		}
+	}
+
+	if len(text) == 0 || strings.ContainsAny(text, " =\"\t\r\n\\") {
+		return fmt.Sprintf("%q", text)
+	}
+	return text
+}
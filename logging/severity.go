@@ -0,0 +1,154 @@
+package logging
+
+import (
+	"runtime"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// Severity values as defined by Google Cloud Logging. Not all of them are
+// used by Severities, they can be used to change the mapping.
+// See https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#logseverity
+const (
+	SeverityDefault   = "DEFAULT"
+	SeverityDebug     = "DEBUG"
+	SeverityInfo      = "INFO"
+	SeverityNotice    = "NOTICE"
+	SeverityWarning   = "WARNING"
+	SeverityError     = "ERROR"
+	SeverityCritical  = "CRITICAL"
+	SeverityAlert     = "ALERT"
+	SeverityEmergency = "EMERGENCY"
+)
+
+// Special field names understood by Google Cloud Logging.
+// See https://cloud.google.com/logging/docs/structured-logging#structured_logging_special_fields
+const (
+	// SourceLocationKey is the field name for a SourceLocation.
+	SourceLocationKey = "logging.googleapis.com/sourceLocation"
+	// LabelsKey is the field name for Labels.
+	LabelsKey = "logging.googleapis.com/labels"
+	// OperationKey is the field name for an Operation.
+	OperationKey = "logging.googleapis.com/operation"
+)
+
+// Severities maps zerolog levels to Google Cloud Logging severities.
+// Levels missing from this map are written as SeverityDefault.
+// Modify this map before the first log call to change the mapping.
+var Severities = map[zerolog.Level]string{
+	zerolog.TraceLevel: SeverityDebug,
+	zerolog.DebugLevel: SeverityDebug,
+	zerolog.InfoLevel:  SeverityInfo,
+	zerolog.WarnLevel:  SeverityWarning,
+	zerolog.ErrorLevel: SeverityError,
+	zerolog.FatalLevel: SeverityCritical,
+	zerolog.PanicLevel: SeverityAlert,
+}
+
+// Labels is a set of user-defined key/value pairs attached to a log entry.
+// Use it with zerolog's Object function and LabelsKey.
+type Labels map[string]string
+
+// Operation identifies a group of related log entries, e.g. all entries
+// written during a single request. Use it with zerolog's Object function and
+// OperationKey.
+type Operation struct {
+	// ID is an identifier shared by all entries of the operation.
+	ID string
+	// Producer identifies the component that writes the entries.
+	Producer string
+	// First marks the first entry of the operation.
+	First bool
+	// Last marks the last entry of the operation.
+	Last bool
+}
+
+// SourceLocation identifies the source code that wrote a log entry. Use it
+// with zerolog's Object function and SourceLocationKey, or enable
+// Options.SourceLocation to add it to every log entry.
+type SourceLocation struct {
+	// File is the source file name.
+	File string
+	// Line is the line within the source file.
+	Line int
+	// Function is the fully qualified function name.
+	Function string
+}
+
+// sourceLocationHook adds the SourceLocation of the log call to each event.
+type sourceLocationHook struct{}
+
+// Severity returns the Google Cloud Logging severity for a zerolog level.
+func Severity(level zerolog.Level) string {
+	if severity, ok := Severities[level]; ok {
+		return severity
+	}
+	return SeverityDefault
+}
+
+// MarshalZerologObject writes the labels as a JSON object.
+func (labels Labels) MarshalZerologObject(event *zerolog.Event) {
+	for key, value := range labels {
+		event.Str(key, value)
+	}
+}
+
+// MarshalZerologObject writes the operation as a JSON object.
+func (operation Operation) MarshalZerologObject(event *zerolog.Event) {
+	event.Str("id", operation.ID)
+	if len(operation.Producer) > 0 {
+		event.Str("producer", operation.Producer)
+	}
+	if operation.First {
+		event.Bool("first", true)
+	}
+	if operation.Last {
+		event.Bool("last", true)
+	}
+}
+
+// MarshalZerologObject writes the source location as a JSON object.
+func (location SourceLocation) MarshalZerologObject(event *zerolog.Event) {
+	event.Str("file", location.File).
+		Int("line", location.Line).
+		Str("function", location.Function)
+}
+
+// Run adds the location of the first caller outside of zerolog to the event.
+func (sourceLocationHook) Run(event *zerolog.Event, _ zerolog.Level, _ string) {
+	if location, ok := callerLocation(); ok {
+		event.Object(SourceLocationKey, location)
+	}
+}
+
+// callerLocation returns the location of the first stack frame that is not
+// part of zerolog, slog, this package's slog handler or the go runtime.
+func callerLocation() (SourceLocation, bool) {
+	callers := make([]uintptr, 16)
+	count := runtime.Callers(3, callers)
+	frames := runtime.CallersFrames(callers[:count])
+
+	for {
+		frame, more := frames.Next()
+		if !isLoggingFrame(frame.Function) {
+			return SourceLocation{
+				File:     frame.File,
+				Line:     frame.Line,
+				Function: frame.Function,
+			}, true
+		}
+		if !more {
+			return SourceLocation{}, false
+		}
+	}
+}
+
+// isLoggingFrame reports whether function belongs to the logging machinery
+// and should be skipped when looking for the log call site.
+func isLoggingFrame(function string) bool {
+	return strings.HasPrefix(function, "github.com/rs/zerolog") ||
+		strings.HasPrefix(function, "log/slog.") ||
+		strings.HasPrefix(function, "github.com/trivago/go-bootstrap/v2/logging.(*SlogHandler)") ||
+		strings.HasPrefix(function, "runtime.")
+}
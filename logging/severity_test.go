@@ -0,0 +1,70 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSeverity verifies the mapping of zerolog levels to Google Cloud
+// Logging severities.
+func TestSeverity(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// level is the zerolog level to map.
+		level zerolog.Level
+		// want is the expected severity.
+		want string
+	}{
+		{level: zerolog.TraceLevel, want: SeverityDebug},
+		{level: zerolog.DebugLevel, want: SeverityDebug},
+		{level: zerolog.InfoLevel, want: SeverityInfo},
+		{level: zerolog.WarnLevel, want: SeverityWarning},
+		{level: zerolog.ErrorLevel, want: SeverityError},
+		{level: zerolog.FatalLevel, want: SeverityCritical},
+		{level: zerolog.PanicLevel, want: SeverityAlert},
+		{level: zerolog.NoLevel, want: SeverityDefault},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, Severity(tt.level))
+		})
+	}
+}
+
+// TestCloudLoggingFields verifies severity, labels, operation and source
+// location are written in the Google Cloud Logging format. The test
+// modifies the global logger and must not run in parallel.
+func TestCloudLoggingFields(t *testing.T) {
+	t.Cleanup(func() {
+		require.NoError(t, Configure(Options{}))
+	})
+
+	output := &bytes.Buffer{}
+	require.NoError(t, Configure(Options{Output: output, SourceLocation: true}))
+
+	log.Warn().
+		Object(LabelsKey, Labels{"team": "platform"}).
+		Object(OperationKey, Operation{ID: "42", Producer: "test", First: true}).
+		Msg("hello")
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(output.Bytes(), &entry))
+
+	assert.Equal(t, SeverityWarning, entry["severity"])
+	assert.Equal(t, map[string]any{"team": "platform"}, entry[LabelsKey])
+	assert.Equal(t, map[string]any{"id": "42", "producer": "test", "first": true}, entry[OperationKey])
+
+	location, ok := entry[SourceLocationKey].(map[string]any)
+	require.True(t, ok)
+	assert.Contains(t, location["file"], "severity_test.go")
+	assert.Contains(t, location["function"], "TestCloudLoggingFields")
+}
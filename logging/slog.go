@@ -0,0 +1,217 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// SlogHandler is a slog.Handler writing through zerolog. Attributes keep
+// their type, groups are written as nested JSON objects and slog levels are
+// mapped to the closest zerolog level.
+type SlogHandler struct {
+	// logger receives all records. When nil, the global logger is used at
+	// the time a record is handled.
+	logger *zerolog.Logger
+	// scopes holds the attributes and groups added through WithAttrs and
+	// WithGroup, in order.
+	scopes []slogScope
+}
+
+// slogScope is either a group opened through WithGroup or a list of
+// attributes added through WithAttrs.
+type slogScope struct {
+	// group is the name of the group, or empty for attributes.
+	group string
+	// attrs are the attributes added through WithAttrs.
+	attrs []slog.Attr
+}
+
+// NewSlogHandler creates a slog.Handler writing to the given zerolog logger.
+// When logger is nil, records are written to the global logger, including
+// any later changes through Configure or SetLogLevel.
+func NewSlogHandler(logger *zerolog.Logger) *SlogHandler {
+	return &SlogHandler{logger: logger}
+}
+
+// SetSlogDefault installs a SlogHandler writing to the global logger as the
+// handler of slog.Default. Output of the standard library's log package is
+// redirected to the same handler by slog.
+func SetSlogDefault() {
+	slog.SetDefault(slog.New(NewSlogHandler(nil)))
+}
+
+// Enabled reports whether records of the given level are written.
+func (handler *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	zerologLevel := slogToZerologLevel(level)
+	if zerologLevel < zerolog.GlobalLevel() {
+		return false
+	}
+	if handler.logger == nil {
+		return zerologLevel >= effectiveRootLevel()
+	}
+	return zerologLevel >= handler.logger.GetLevel()
+}
+
+// Handle writes a record as a zerolog event.
+func (handler *SlogHandler) Handle(ctx context.Context, record slog.Record) error {
+	logger := handler.logger
+	if logger == nil {
+		logger = &log.Logger
+	}
+
+	event := logger.WithLevel(slogToZerologLevel(record.Level))
+	if event == nil {
+		return nil
+	}
+
+	if ctx != nil {
+		event = event.Ctx(ctx)
+	}
+
+	appendSlogScopes(event, handler.scopes, record)
+	event.Msg(record.Message)
+	return nil
+}
+
+// WithAttrs returns a handler adding the given attributes to all records.
+func (handler *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return handler
+	}
+	return handler.withScope(slogScope{attrs: attrs})
+}
+
+// WithGroup returns a handler nesting all following attributes in a group
+// of the given name.
+func (handler *SlogHandler) WithGroup(name string) slog.Handler {
+	if len(name) == 0 {
+		return handler
+	}
+	return handler.withScope(slogScope{group: name})
+}
+
+// withScope returns a copy of handler with scope appended.
+func (handler *SlogHandler) withScope(scope slogScope) *SlogHandler {
+	scopes := make([]slogScope, 0, len(handler.scopes)+1)
+	scopes = append(scopes, handler.scopes...)
+	return &SlogHandler{
+		logger: handler.logger,
+		scopes: append(scopes, scope),
+	}
+}
+
+// appendSlogScopes writes the attributes of all scopes and the record to
+// event. Groups are written as nested objects that are omitted when empty.
+// Returns the number of fields written.
+func appendSlogScopes(event *zerolog.Event, scopes []slogScope, record slog.Record) int {
+	written := 0
+	for i, scope := range scopes {
+		if len(scope.group) == 0 {
+			for _, attr := range scope.attrs {
+				written += appendSlogAttr(event, attr)
+			}
+			continue
+		}
+
+		dict := zerolog.Dict()
+		if appendSlogScopes(dict, scopes[i+1:], record) > 0 {
+			event.Dict(scope.group, dict)
+			written++
+		}
+		return written
+	}
+
+	record.Attrs(func(attr slog.Attr) bool {
+		written += appendSlogAttr(event, attr)
+		return true
+	})
+	return written
+}
+
+// appendSlogAttr writes a single attribute to event, keeping its type where
+// possible. Returns the number of fields written.
+func appendSlogAttr(event *zerolog.Event, attr slog.Attr) int {
+	value := attr.Value.Resolve()
+
+	if value.Kind() == slog.KindGroup {
+		attrs := value.Group()
+		if len(attr.Key) == 0 {
+			// Groups without a key are inlined.
+			written := 0
+			for _, member := range attrs {
+				written += appendSlogAttr(event, member)
+			}
+			return written
+		}
+
+		dict := zerolog.Dict()
+		written := 0
+		for _, member := range attrs {
+			written += appendSlogAttr(dict, member)
+		}
+		if written == 0 {
+			return 0
+		}
+		event.Dict(attr.Key, dict)
+		return 1
+	}
+
+	if len(attr.Key) == 0 {
+		return 0
+	}
+
+	switch value.Kind() {
+	case slog.KindString:
+		event.Str(attr.Key, value.String())
+	case slog.KindInt64:
+		event.Int64(attr.Key, value.Int64())
+	case slog.KindUint64:
+		event.Uint64(attr.Key, value.Uint64())
+	case slog.KindFloat64:
+		event.Float64(attr.Key, value.Float64())
+	case slog.KindBool:
+		event.Bool(attr.Key, value.Bool())
+	case slog.KindDuration:
+		event.Dur(attr.Key, value.Duration())
+	case slog.KindTime:
+		event.Time(attr.Key, value.Time())
+	default:
+		switch typed := value.Any().(type) {
+		case error:
+			event.AnErr(attr.Key, typed)
+		case time.Duration:
+			event.Dur(attr.Key, typed)
+		case []byte:
+			event.Bytes(attr.Key, typed)
+		default:
+			event.Interface(attr.Key, typed)
+		}
+	}
+	return 1
+}
+
+// slogToZerologLevel maps slog levels to the closest zerolog level. Levels
+// above slog.LevelError are mapped to the fatal and panic levels, without
+// terminating the program.
+func slogToZerologLevel(level slog.Level) zerolog.Level {
+	switch {
+	case level < slog.LevelDebug:
+		return zerolog.TraceLevel
+	case level < slog.LevelInfo:
+		return zerolog.DebugLevel
+	case level < slog.LevelWarn:
+		return zerolog.InfoLevel
+	case level < slog.LevelError:
+		return zerolog.WarnLevel
+	case level < slog.LevelError+4:
+		return zerolog.ErrorLevel
+	case level < slog.LevelError+8:
+		return zerolog.FatalLevel
+	default:
+		return zerolog.PanicLevel
+	}
+}
@@ -0,0 +1,112 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSlogHandler verifies that attributes, groups and levels are preserved
+// when logging through slog.
+func TestSlogHandler(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		// name identifies the test case.
+		name string
+		// write writes a record through the given slog logger.
+		write func(logger *slog.Logger)
+		// want is the expected JSON object, without the message.
+		want map[string]any
+	}{
+		{
+			name: "typed attributes",
+			write: func(logger *slog.Logger) {
+				logger.Info("msg", "str", "value", "int", 42, "bool", true, "err", errors.New("boom"))
+			},
+			want: map[string]any{
+				"severity": SeverityInfo,
+				"str":      "value",
+				"int":      float64(42),
+				"bool":     true,
+				"err":      "boom",
+			},
+		},
+		{
+			name: "nested groups",
+			write: func(logger *slog.Logger) {
+				logger.With("outer", 1).WithGroup("request").With("method", "GET").
+					Warn("msg", slog.Group("client", "ip", "10.0.0.1"))
+			},
+			want: map[string]any{
+				"severity": SeverityWarning,
+				"outer":    float64(1),
+				"request": map[string]any{
+					"method": "GET",
+					"client": map[string]any{"ip": "10.0.0.1"},
+				},
+			},
+		},
+		{
+			name: "empty groups are omitted",
+			write: func(logger *slog.Logger) {
+				logger.WithGroup("empty").Error("msg", slog.Group("none"))
+			},
+			want: map[string]any{
+				"severity": SeverityError,
+			},
+		},
+		{
+			name: "inlined group without key",
+			write: func(logger *slog.Logger) {
+				logger.Debug("msg", slog.Group("", "inline", "yes"))
+			},
+			want: map[string]any{
+				"severity": SeverityDebug,
+				"inline":   "yes",
+			},
+		},
+		{
+			name: "levels above error",
+			write: func(logger *slog.Logger) {
+				logger.Log(t.Context(), slog.LevelError+4, "msg")
+			},
+			want: map[string]any{
+				"severity": SeverityCritical,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			output := &bytes.Buffer{}
+			zerologger := zerolog.New(output)
+			tt.write(slog.New(NewSlogHandler(&zerologger)))
+
+			var got map[string]any
+			require.NoError(t, json.Unmarshal(output.Bytes(), &got))
+			assert.Equal(t, "msg", got["message"])
+			delete(got, "message")
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// TestSlogHandlerEnabled verifies that the logger level is respected.
+func TestSlogHandlerEnabled(t *testing.T) {
+	t.Parallel()
+
+	zerologger := zerolog.New(&bytes.Buffer{}).Level(zerolog.WarnLevel)
+	handler := NewSlogHandler(&zerologger)
+
+	assert.False(t, handler.Enabled(t.Context(), slog.LevelInfo))
+	assert.True(t, handler.Enabled(t.Context(), slog.LevelWarn))
+}